@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"shared"
+)
+
+// Настройки читаются через shared.LoadSettings: флаги, переменные окружения,
+// файл настроек и значения по умолчанию.
+
+// Config - настройки сервера страниц
+type Config struct {
+	shared.ServerConfig
+	// HTMLDir - каталог со страницами и favicon.svg
+	HTMLDir string
+	// UpstreamURL - адрес mock-server, к которому обращаются страницы
+	UpstreamURL string
+	// CSP - значение Content-Security-Policy, пустое - заголовок не отправляется
+	CSP string
+}
+
+// rawConfig - настройки в текстовом виде до проверки
+type rawConfig struct {
+	shared.RawServerConfig
+	HTMLDir, UpstreamURL, CSP string
+}
+
+func (c *rawConfig) settings() []shared.Setting {
+	return append(c.RawServerConfig.Settings(), []shared.Setting{
+		{Key: "html_dir", Env: "HTML_DIR", Usage: "каталог со страницами", Value: &c.HTMLDir},
+		{Key: "upstream_url", Env: "UPSTREAM_URL", Usage: "адрес mock-server для запросов страниц и проверки готовности", Value: &c.UpstreamURL},
+		{Key: "csp", Env: "CONTENT_SECURITY_POLICY", Usage: "значение Content-Security-Policy, {upstream} - адрес mock-server, пустое - не отправлять", Value: &c.CSP},
+	}...)
+}
+
+var defaultRawConfig = rawConfig{
+	RawServerConfig: shared.RawServerConfig{
+		Listen:     ":8080",
+		DevTLS:     "false",
+		HSTSMaxAge: "31536000",
+		LogLevel:   "info",
+		LogOutput:  "stdout",
+	},
+	HTMLDir:     ".",
+	UpstreamURL: "http://localhost",
+	CSP:         defaultCSP,
+}
+
+// loadConfig собирает настройки из аргументов командной строки, окружения и файла
+func loadConfig(args []string) (Config, error) {
+	raw := defaultRawConfig
+	if err := shared.LoadSettings("server", args, raw.settings()); err != nil {
+		return Config{}, err
+	}
+	return raw.validate()
+}
+
+// validate проверяет настройки и приводит их к рабочему виду
+func (c rawConfig) validate() (Config, error) {
+	server, err := c.RawServerConfig.Validate()
+	if err != nil {
+		return Config{}, err
+	}
+	cfg := Config{
+		ServerConfig: server,
+		HTMLDir:      filepath.Clean(c.HTMLDir),
+		CSP:          strings.TrimSpace(c.CSP),
+	}
+
+	if info, err := os.Stat(cfg.HTMLDir); err != nil || !info.IsDir() {
+		return Config{}, fmt.Errorf("html_dir: каталог %q не найден", c.HTMLDir)
+	}
+
+	u, err := url.Parse(c.UpstreamURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Config{}, fmt.Errorf("upstream_url: некорректный адрес %q", c.UpstreamURL)
+	}
+	cfg.UpstreamURL = strings.TrimSuffix(c.UpstreamURL, "/")
+	cfg.CSP = strings.ReplaceAll(cfg.CSP, cspUpstream, u.Scheme+"://"+u.Host)
+	return cfg, nil
+}
@@ -1,3 +1,7 @@
 module server
 
 go 1.25.0
+
+require shared v0.0.0
+
+replace shared => ../../shared
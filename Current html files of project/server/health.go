@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"shared"
+)
+
+// Проверки состояния отдает shared, здесь - проверки готовности сервера
+// страниц: страницы доступны и mock-server отвечает.
+
+// buildTime задается при сборке: -ldflags "-X main.buildTime=2026-01-01T00:00:00Z"
+var buildTime string
+
+// Страницы, без которых сервер не готов принимать запросы
+var pages = []string{"main.html", "vacancy.html", "employer.html", "favicon.svg"}
+
+// upstreamURL - адрес mock-server, к которому обращаются страницы
+var upstreamURL = "http://localhost"
+
+var upstreamClient = &http.Client{Timeout: 2 * time.Second}
+
+// trustDevCA добавляет корневой сертификат разработки к доверенным для запросов к mock-server
+func trustDevCA(dir string) error {
+	transport, err := shared.DevCATransport(dir)
+	if err != nil {
+		return err
+	}
+	upstreamClient.Transport = transport
+	return nil
+}
+
+// checkPages проверяет, что файлы страниц есть и читаются
+func checkPages() error {
+	for _, name := range pages {
+		f, err := os.Open(filepath.Join(htmlDir, name))
+		if err != nil {
+			return err
+		}
+		f.Close()
+	}
+	return nil
+}
+
+// checkUpstream проверяет, что mock-server жив
+func checkUpstream() error {
+	resp, err := upstreamClient.Get(upstreamURL + "/healthz")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s/healthz: %s", upstreamURL, resp.Status)
+	}
+	return nil
+}
+
+var readinessChecks = []shared.ReadinessCheck{
+	{Name: "pages", Check: checkPages},
+	{Name: "upstream", Check: checkUpstream},
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"shared"
+)
+
+// Метрики отдаются на /metrics в текстовом формате Prometheus, как и у mock-server:
+// счетчики HTTP-запросов из shared и обращения к статическим файлам.
+
+var fileHits struct {
+	sync.Mutex
+	files map[string]uint64
+}
+
+// serveFile отдает статический файл и учитывает обращение к нему
+func serveFile(w http.ResponseWriter, r *http.Request, name string) {
+	fileHits.Lock()
+	if fileHits.files == nil {
+		fileHits.files = map[string]uint64{}
+	}
+	fileHits.files[name]++
+	fileHits.Unlock()
+
+	http.ServeFile(w, r, filepath.Join(htmlDir, name))
+}
+
+// serveMetrics - GET /metrics
+func serveMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	shared.WriteHTTPMetrics(w)
+
+	fileHits.Lock()
+	defer fileHits.Unlock()
+	shared.WriteMetricHeader(w, "static_file_hits_total", "counter", "Обращения к статическим файлам.")
+	for _, name := range slices.Sorted(maps.Keys(fileHits.files)) {
+		fmt.Fprintf(w, "static_file_hits_total{file=\"%s\"} %d\n", shared.EscapeLabel(name), fileHits.files[name])
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import "net/http"
+
+// cspUpstream в настройке csp заменяется источником mock-server из upstream_url
+const cspUpstream = "{upstream}"
+
+// defaultCSP разрешает страницам только собственные ресурсы и запросы к
+// mock-server. Встроенные скрипты, стили и обработчики onclick в страницах
+// требуют 'unsafe-inline'.
+const defaultCSP = "default-src 'self'; script-src 'self' 'unsafe-inline'; style-src 'self' 'unsafe-inline'; " +
+	"img-src 'self' data:; connect-src 'self' " + cspUpstream + "; " +
+	"object-src 'none'; base-uri 'self'; form-action 'self'; frame-ancestors 'none'"
+
+// securityHeadersMiddleware добавляет заголовки, ограничивающие браузер:
+// источники ресурсов, встраивание во фреймы, передачу Referer и угадывание типа
+func securityHeadersMiddleware(csp string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		if csp != "" {
+			h.Set("Content-Security-Policy", csp)
+		}
+		h.Set("X-Frame-Options", "DENY")
+		h.Set("Referrer-Policy", "strict-origin-when-cross-origin")
+		h.Set("X-Content-Type-Options", "nosniff")
+		next.ServeHTTP(w, r)
+	})
+}
@@ -1,35 +1,83 @@
 package main
 
 import (
-	"log"
+	"errors"
+	"flag"
+	"log/slog"
 	"net/http"
+	"os"
+	"time"
+
+	"shared"
 )
 
+// htmlDir - каталог со страницами
+var htmlDir = "."
+
+// readTimeout - время на чтение запроса: страницы принимают только GET
+const readTimeout = 10 * time.Second
+
 func main() {
+	cfg, err := loadConfig(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		slog.Error("некорректные настройки", "error", err)
+		os.Exit(2)
+	}
+	if err := shared.SetupLogger(cfg.LogLevel, cfg.LogOutput); err != nil {
+		slog.Error("не удалось настроить журнал", "error", err)
+		os.Exit(1)
+	}
+	htmlDir = cfg.HTMLDir
+	upstreamURL = cfg.UpstreamURL
+
 	// Настройка маршрутов
 	http.HandleFunc("/", serveMain)
 	http.HandleFunc("/main.html", serveMain)
 	http.HandleFunc("/vacancy.html", serveVacancy)
 	http.HandleFunc("/employer.html", serveEmployer)
 	http.HandleFunc("/favicon.svg", favicon)
+	http.HandleFunc("GET /metrics", serveMetrics)
+	http.HandleFunc("GET /healthz", shared.Healthz)
+	http.HandleFunc("GET /readyz", shared.Readyz(readinessChecks...))
+	http.HandleFunc("GET /version", shared.Version(buildTime))
+
+	handler := securityHeadersMiddleware(cfg.CSP, http.DefaultServeMux)
+	servers, err := shared.NewServers(cfg.ServerConfig, handler, readTimeout, nil)
+	if err != nil {
+		slog.Error("не удалось загрузить сертификат", "error", err)
+		os.Exit(1)
+	}
+	// mock-server в режиме разработки использует тот же корневой сертификат
+	if cfg.DevTLS {
+		if err := trustDevCA(cfg.DevTLSDir); err != nil {
+			slog.Error("не удалось загрузить корневой сертификат разработки", "error", err)
+			os.Exit(1)
+		}
+	}
 
-	// Запуск сервера на порту 8080
-	log.Println("Сервер запущен на http://localhost:8080")
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	slog.Info("Сервер запущен", "addr", cfg.Listen, "tls", servers[0].TLSConfig != nil, "redirect", cfg.RedirectListen)
+	if err := shared.RunServers(nil, servers...); err != nil {
+		slog.Error("сервер остановлен", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("сервер остановлен")
 }
 
 func serveMain(w http.ResponseWriter, r *http.Request) {
-	http.ServeFile(w, r, "main.html")
+	serveFile(w, r, "main.html")
 }
 
 func serveVacancy(w http.ResponseWriter, r *http.Request) {
-	http.ServeFile(w, r, "vacancy.html")
+	serveFile(w, r, "vacancy.html")
 }
 
 func serveEmployer(w http.ResponseWriter, r *http.Request) {
-	http.ServeFile(w, r, "employer.html")
+	serveFile(w, r, "employer.html")
 }
 
 func favicon(w http.ResponseWriter, r *http.Request) {
-	http.ServeFile(w, r, "favicon.svg")
+	serveFile(w, r, "favicon.svg")
 }
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log/slog"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Учетные записи берутся у AccountProvider: из встроенных данных (LocalAccounts)
+// или из каталога университета (LDAPAccounts). accountsDB служит кэшем:
+// каталог периодически копируется в него целиком, а логины, которых в кэше
+// еще нет, запрашиваются у каталога при входе.
+
+// AccountProvider определяет роли пользователя по логину
+type AccountProvider interface {
+	// Lookup возвращает учетную запись по логину; false - пользователя нет
+	Lookup(ctx context.Context, login string) (Account, bool, error)
+	// List возвращает все учетные записи для синхронизации
+	List(ctx context.Context) (map[string]Account, error)
+}
+
+// accountProvider - источник учетных записей. По умолчанию - встроенные
+// данные или seed_file.
+var accountProvider AccountProvider
+
+const (
+	ldapTimeout = 10 * time.Second
+	// accountMissTTL - сколько помнить, что логина нет в каталоге,
+	// чтобы перебор логинов не превращался в запросы к каталогу
+	accountMissTTL = time.Minute
+)
+
+// LocalAccounts - учетные записи из встроенных данных или seed_file
+type LocalAccounts struct {
+	Accounts map[string]Account
+}
+
+func (l LocalAccounts) Lookup(ctx context.Context, login string) (Account, bool, error) {
+	a, ok := l.Accounts[login]
+	return a, ok, nil
+}
+
+func (l LocalAccounts) List(ctx context.Context) (map[string]Account, error) {
+	return maps.Clone(l.Accounts), nil
+}
+
+// LDAPConfig - подключение к каталогу LDAP или Active Directory
+type LDAPConfig struct {
+	// URL - ldap://host:389 или ldaps://host:636
+	URL          string
+	BindDN       string
+	BindPassword string
+	// BaseDN - поддерево, в котором ищутся пользователи
+	BaseDN string
+	// LoginAttr - атрибут с логином: uid или sAMAccountName
+	LoginAttr string
+	// SyncInterval - период полной синхронизации, 0 - только поиск при входе
+	SyncInterval time.Duration
+}
+
+// Атрибуты записи пользователя в каталоге
+const (
+	ldapStudentAttr      = "employeeNumber"   // СНИЛС студента
+	ldapOrganizationAttr = "departmentNumber" // GUID организации
+	ldapGroupsAttr       = "memberOf"
+)
+
+// ldapRoleGroups сопоставляет группы каталога (по CN) ролям сотрудников.
+// Порядок задает старшинство, если пользователь состоит в нескольких.
+var ldapRoleGroups = []struct {
+	cn, role string
+}{
+	{"jobservice-admins", RoleAdmin},
+	{"jobservice-moderators", RoleModerator},
+	{"jobservice-support", RoleSupport},
+}
+
+// LDAPAccounts - учетные записи из каталога университета
+type LDAPAccounts struct {
+	cfg       LDAPConfig
+	tlsConfig *tls.Config
+}
+
+func NewLDAPAccounts(cfg LDAPConfig, tlsConfig *tls.Config) *LDAPAccounts {
+	return &LDAPAccounts{cfg: cfg, tlsConfig: tlsConfig}
+}
+
+func (l *LDAPAccounts) search(ctx context.Context, filter []byte, limit int) ([]ldapEntry, error) {
+	conn, err := dialLDAP(ctx, l.cfg.URL, l.tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.close()
+	if l.cfg.BindDN != "" {
+		if err := conn.bind(l.cfg.BindDN, l.cfg.BindPassword); err != nil {
+			return nil, err
+		}
+	}
+	attrs := []string{l.cfg.LoginAttr, ldapStudentAttr, ldapOrganizationAttr, ldapGroupsAttr}
+	return conn.search(l.cfg.BaseDN, filter, attrs, limit)
+}
+
+func (l *LDAPAccounts) Lookup(ctx context.Context, login string) (Account, bool, error) {
+	entries, err := l.search(ctx, ldapEqual(l.cfg.LoginAttr, login), 2)
+	if err != nil {
+		return Account{}, false, err
+	}
+	switch len(entries) {
+	case 0:
+		return Account{}, false, nil
+	case 1:
+		return ldapAccount(entries[0]), true, nil
+	}
+	return Account{}, false, errors.New("в каталоге несколько записей с этим логином")
+}
+
+func (l *LDAPAccounts) List(ctx context.Context) (map[string]Account, error) {
+	entries, err := l.search(ctx, ldapPresent(l.cfg.LoginAttr), 0)
+	if err != nil {
+		return nil, err
+	}
+	accounts := make(map[string]Account, len(entries))
+	for _, e := range entries {
+		if login := e.first(l.cfg.LoginAttr); login != "" {
+			accounts[login] = ldapAccount(e)
+		}
+	}
+	return accounts, nil
+}
+
+// ldapAccount переводит запись каталога в учетную запись платформы
+func ldapAccount(e ldapEntry) Account {
+	a := Account{
+		Student:      e.first(ldapStudentAttr),
+		Organization: e.first(ldapOrganizationAttr),
+		Groups:       e.attrs[strings.ToLower(ldapGroupsAttr)],
+	}
+	for _, g := range ldapRoleGroups {
+		for _, dn := range a.Groups {
+			if strings.EqualFold(groupCN(dn), g.cn) {
+				a.Role = g.role
+				return a
+			}
+		}
+	}
+	return a
+}
+
+// groupCN возвращает значение CN из первого компонента DN группы
+func groupCN(dn string) string {
+	rdn, _, _ := strings.Cut(dn, ",")
+	attr, value, ok := strings.Cut(rdn, "=")
+	if !ok || !strings.EqualFold(strings.TrimSpace(attr), "cn") {
+		return ""
+	}
+	return strings.TrimSpace(value)
+}
+
+// accountsMu защищает accountsDB. Это отдельная блокировка, а не dataMu:
+// учетные записи читаются и там, где dataMu уже захвачен, например в audit.
+var accountsMu sync.RWMutex
+
+// cachedAccount возвращает учетную запись из кэша, не обращаясь к каталогу.
+// Годится там, где пользователь уже вошел, а ждать каталог нельзя.
+func cachedAccount(login string) (Account, bool) {
+	accountsMu.RLock()
+	defer accountsMu.RUnlock()
+	account, ok := accountsDB[login]
+	return account, ok
+}
+
+// findAccount возвращает первую учетную запись из кэша, для которой match истинно
+func findAccount(match func(Account) bool) (string, Account, bool) {
+	accountsMu.RLock()
+	defer accountsMu.RUnlock()
+	for login, a := range accountsDB {
+		if match(a) {
+			return login, a, true
+		}
+	}
+	return "", Account{}, false
+}
+
+var accountMisses struct {
+	sync.Mutex
+	m map[string]time.Time
+}
+
+// lookupAccount возвращает учетную запись из кэша, а если ее там нет -
+// запрашивает у accountProvider и кэширует
+func lookupAccount(ctx context.Context, login string) (Account, bool) {
+	account, ok := cachedAccount(login)
+	if ok || login == "" || accountProvider == nil {
+		return account, ok
+	}
+
+	now := time.Now()
+	accountMisses.Lock()
+	missed, recent := accountMisses.m[login]
+	accountMisses.Unlock()
+	if recent && now.Sub(missed) < accountMissTTL {
+		return Account{}, false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, ldapTimeout)
+	defer cancel()
+	account, ok, err := accountProvider.Lookup(ctx, login)
+	if err != nil {
+		slog.Error("не удалось найти учетную запись в каталоге", "error", err)
+		return Account{}, false
+	}
+	if !ok {
+		accountMisses.Lock()
+		if accountMisses.m == nil {
+			accountMisses.m = map[string]time.Time{}
+		}
+		for l, t := range accountMisses.m {
+			if now.Sub(t) >= accountMissTTL {
+				delete(accountMisses.m, l)
+			}
+		}
+		accountMisses.m[login] = now
+		accountMisses.Unlock()
+		return Account{}, false
+	}
+	accountsMu.Lock()
+	accountsDB[login] = account
+	accountsMu.Unlock()
+	return account, true
+}
+
+// accountSync - результат последней синхронизации для /readyz
+var accountSync struct {
+	sync.Mutex
+	interval time.Duration
+	last     time.Time
+	err      error
+}
+
+// syncAccounts заменяет кэш учетных записей содержимым каталога
+func syncAccounts(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*ldapTimeout)
+	defer cancel()
+	accounts, err := accountProvider.List(ctx)
+
+	accountSync.Lock()
+	accountSync.err = err
+	if err == nil {
+		accountSync.last = time.Now()
+	}
+	accountSync.Unlock()
+	if err != nil {
+		return err
+	}
+
+	accountsMu.Lock()
+	accountsDB = accounts
+	accountsMu.Unlock()
+	accountMisses.Lock()
+	clear(accountMisses.m)
+	accountMisses.Unlock()
+	slog.Info("учетные записи синхронизированы с каталогом", "accounts", len(accounts))
+	return nil
+}
+
+// startAccountSync синхронизирует учетные записи при запуске и затем
+// с периодом interval
+func startAccountSync(interval time.Duration) {
+	accountSync.Lock()
+	accountSync.interval = interval
+	accountSync.Unlock()
+	if err := syncAccounts(context.Background()); err != nil {
+		slog.Error("не удалось синхронизировать учетные записи", "error", err)
+	}
+	go func() {
+		for range time.Tick(interval) {
+			if err := syncAccounts(context.Background()); err != nil {
+				slog.Error("не удалось синхронизировать учетные записи", "error", err)
+			}
+		}
+	}()
+}
+
+// checkAccountSync - каталог был прочитан хотя бы раз, и последняя
+// синхронизация прошла успешно. Без синхронизации проверять нечего.
+func checkAccountSync() error {
+	accountSync.Lock()
+	defer accountSync.Unlock()
+	if accountSync.interval == 0 {
+		return nil
+	}
+	if accountSync.err != nil {
+		return accountSync.err
+	}
+	if accountSync.last.IsZero() {
+		return errors.New("каталог еще не прочитан")
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BlobStore - хранилище файлов, прикрепленных к откликам
+type BlobStore interface {
+	Put(key string, r io.Reader) error
+	Get(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+// FSBlobStore хранит файлы в каталоге локальной файловой системы
+type FSBlobStore struct {
+	Dir string
+}
+
+var errInvalidBlobKey = errors.New("некорректный ключ файла")
+
+func (s FSBlobStore) path(key string) (string, error) {
+	if key == "" || strings.ContainsAny(key, `/\.`) {
+		return "", errInvalidBlobKey
+	}
+	return filepath.Join(s.Dir, key), nil
+}
+
+func (s FSBlobStore) Put(key string, r io.Reader) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(p)
+		return err
+	}
+	return f.Close()
+}
+
+func (s FSBlobStore) Get(key string) (io.ReadCloser, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+func (s FSBlobStore) Delete(key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	return os.Remove(p)
+}
+
+// Attachment - сведения о файле резюме или портфолио, приложенном к отклику
+type Attachment struct {
+	ID           string    `json:"ID"`
+	Student      string    `json:"Student"`
+	Organization string    `json:"Organization"`
+	Vacancy      string    `json:"Vacancy"`
+	FileName     string    `json:"FileName"`
+	ContentType  string    `json:"ContentType"`
+	Size         int64     `json:"Size"`
+	Uploaded     time.Time `json:"Uploaded"`
+}
+
+const (
+	maxAttachmentSize  = 5 << 20
+	attachmentLinkTTL  = 15 * time.Minute
+	attachmentFormFile = "resume"
+)
+
+// Допустимые типы файлов: PDF, DOCX и изображения
+var allowedAttachmentTypes = map[string]string{
+	"application/pdf": ".pdf",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": ".docx",
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+}
+
+var (
+	blobStore   BlobStore = FSBlobStore{Dir: "uploads"}
+	attachments           = map[string]Attachment{}
+	// attachmentSecret подписывает ссылки на скачивание, новый при каждом запуске сервера
+	attachmentSecret = mustRandomBytes(32)
+)
+
+func mustRandomBytes(n int) []byte {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func newAttachmentID() string {
+	return hex.EncodeToString(mustRandomBytes(16))
+}
+
+// detectAttachmentType определяет тип файла по содержимому.
+// DOCX распознается как zip-архив, поэтому для него дополнительно проверяется расширение.
+func detectAttachmentType(head []byte, fileName string) (string, error) {
+	contentType := http.DetectContentType(head)
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	if contentType == "application/zip" && strings.EqualFold(filepath.Ext(fileName), ".docx") {
+		contentType = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	}
+	if _, ok := allowedAttachmentTypes[contentType]; !ok {
+		return "", fmt.Errorf("недопустимый тип файла: разрешены PDF, DOCX, PNG и JPEG")
+	}
+	return contentType, nil
+}
+
+// saveAttachment проверяет и сохраняет загруженный файл, возвращая его описание
+func saveAttachment(file multipart.File, header *multipart.FileHeader, student string, vacancy Vacancy) (Attachment, error) {
+	if header.Size > maxAttachmentSize {
+		return Attachment{}, fmt.Errorf("размер файла не должен превышать %d МБ", maxAttachmentSize>>20)
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return Attachment{}, fmt.Errorf("не удалось прочитать файл")
+	}
+	contentType, err := detectAttachmentType(head[:n], header.Filename)
+	if err != nil {
+		return Attachment{}, err
+	}
+
+	a := Attachment{
+		ID:           newAttachmentID(),
+		Student:      student,
+		Organization: vacancy.OrganizationID,
+		Vacancy:      vacancy.Number,
+		FileName:     filepath.Base(header.Filename),
+		ContentType:  contentType,
+		Size:         header.Size,
+		Uploaded:     time.Now(),
+	}
+	body := io.MultiReader(bytes.NewReader(head[:n]), io.LimitReader(file, maxAttachmentSize))
+	if err := blobStore.Put(a.ID, body); err != nil {
+		return Attachment{}, fmt.Errorf("не удалось сохранить файл: %v", err)
+	}
+	return a, nil
+}
+
+// deleteAttachmentFile удаляет сохраненный файл отклика, который не дойдет
+// до работодателя, чтобы в хранилище не оставалось файлов без отклика
+func deleteAttachmentFile(id string) {
+	if err := blobStore.Delete(id); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		slog.Warn("не удалось удалить файл", "attachment", id, "error", err)
+	}
+}
+
+// signAttachmentLink подписывает ссылку на скачивание для организации до момента expires
+func signAttachmentLink(id, organization string, expires int64) string {
+	mac := hmac.New(sha256.New, attachmentSecret)
+	fmt.Fprintf(mac, "%s|%s|%d", id, organization, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// 21. Get Attachment Link - GET /JobService/hs/jobservice/attachmentlink/?id=ID&organization=GUID
+// Ссылка выдается только вошедшей организации, на вакансию которой откликнулся студент,
+// и действует attachmentLinkTTL.
+func getAttachmentLink(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	organization, ok := sessionOrganization(w, r, r.URL.Query().Get("organization"))
+	if !ok {
+		return
+	}
+	id := r.URL.Query().Get("id")
+
+	dataMu.RLock()
+	a, ok := attachments[id]
+	dataMu.RUnlock()
+
+	if !ok || a.Organization != organization {
+		writeError(w, http.StatusNotFound, "файл не найден")
+		return
+	}
+
+	expires := time.Now().Add(attachmentLinkTTL).Unix()
+	q := url.Values{}
+	q.Set("id", id)
+	q.Set("organization", organization)
+	q.Set("expires", strconv.FormatInt(expires, 10))
+	q.Set("signature", signAttachmentLink(id, organization, expires))
+
+	requestLogger(r).Info("выдана ссылка на файл", "attachment", id)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{
+		"url":     "/JobService/hs/jobservice/attachment/?" + q.Encode(),
+		"expires": time.Unix(expires, 0).Format(time.RFC3339),
+	})
+}
+
+// 22. Download Attachment - GET /JobService/hs/jobservice/attachment/?id=ID&organization=GUID&expires=...&signature=...
+func downloadAttachment(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	q := r.URL.Query()
+	id := q.Get("id")
+	organization := q.Get("organization")
+	expires, err := strconv.ParseInt(q.Get("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		writeError(w, http.StatusForbidden, "срок действия ссылки истек")
+		return
+	}
+	expected := signAttachmentLink(id, organization, expires)
+	if !hmac.Equal([]byte(expected), []byte(q.Get("signature"))) {
+		writeError(w, http.StatusForbidden, "неверная подпись ссылки")
+		return
+	}
+
+	dataMu.RLock()
+	a, ok := attachments[id]
+	dataMu.RUnlock()
+
+	if !ok || a.Organization != organization {
+		writeError(w, http.StatusNotFound, "файл не найден")
+		return
+	}
+
+	f, err := blobStore.Get(a.ID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "файл не найден")
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", a.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename*=UTF-8''%s", url.PathEscape(a.FileName)))
+	w.Header().Set("Content-Length", strconv.FormatInt(a.Size, 10))
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, f)
+
+	requestLogger(r).Info("файл отправлен", "attachment", a.ID)
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"shared"
+)
+
+// AuditEntry - запись журнала аудита. Журнал только дополняется:
+// записи не изменяются и не удаляются ни одним обработчиком.
+type AuditEntry struct {
+	ID     int             `json:"ID"`
+	Time   time.Time       `json:"Time"`
+	Actor  string          `json:"Actor"`
+	Role   string          `json:"Role"`
+	Action string          `json:"Action"`
+	Target string          `json:"Target"`
+	Before json.RawMessage `json:"Before,omitempty"`
+	After  json.RawMessage `json:"After,omitempty"`
+	IP     string          `json:"IP"`
+}
+
+// Действия, попадающие в журнал аудита
+const (
+	AuditLogin             = "login"
+	AuditVacancyCreate     = "vacancy.create"
+	AuditVacancyClose      = "vacancy.close"
+	AuditVacancyApprove    = "vacancy.approve"
+	AuditVacancyReject     = "vacancy.reject"
+	AuditVacancyTakeDown   = "vacancy.takedown"
+	AuditRequestCreate     = "request.create"
+	AuditRequestApply      = "request.apply"
+	AuditRequestApprove    = "request.approve"
+	AuditRequestReject     = "request.reject"
+	AuditTemplateCreate    = "template.create"
+	AuditTemplateUpdate    = "template.update"
+	AuditTemplateDelete    = "template.delete"
+	AuditProfileSave       = "profile.save"
+	AuditSavedSearchCreate = "savedsearch.create"
+	AuditSavedSearchDelete = "savedsearch.delete"
+	AuditFavoriteAdd       = "favorite.add"
+	AuditFavoriteDelete    = "favorite.delete"
+	AuditTagCreate         = "tag.create"
+	AuditTagUpdate         = "tag.update"
+	AuditTagRename         = "tag.rename"
+	AuditTagMerge          = "tag.merge"
+	AuditTagDelete         = "tag.delete"
+	AuditTicketCreate      = "ticket.create"
+	AuditTicketAssign      = "ticket.assign"
+	AuditTicketReply       = "ticket.reply"
+	AuditTicketClose       = "ticket.close"
+	AuditScreeningUpdate   = "screening.update"
+)
+
+// Роли пользователей без роли сотрудника
+const (
+	RoleOrganization = "organization"
+	RoleStudent      = "student"
+)
+
+// Журнал дублируется в файл в формате JSON Lines и загружается из него при запуске
+const auditLogFile = "audit.log"
+
+// auditLog защищен собственной блокировкой: запись в журнал не должна
+// зависеть от того, держит ли обработчик dataMu.
+var auditLog struct {
+	sync.Mutex
+	entries []AuditEntry
+	file    *os.File
+}
+
+// openAuditLog загружает записи из файла журнала и открывает его для дозаписи.
+// Каталог хранилища создается, если его еще нет.
+func openAuditLog(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	var entries []AuditEntry
+	for scanner.Scan() {
+		var e AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			f.Close()
+			return fmt.Errorf("%s: запись %d: %v", path, len(entries)+1, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		f.Close()
+		return err
+	}
+
+	auditLog.Lock()
+	auditLog.entries = entries
+	auditLog.file = f
+	auditLog.Unlock()
+	slog.Info("журнал аудита открыт", "path", path, "entries", len(entries))
+	return nil
+}
+
+// closeAuditLog сбрасывает файл журнала на диск и закрывает его
+func closeAuditLog() error {
+	auditLog.Lock()
+	defer auditLog.Unlock()
+	if auditLog.file == nil {
+		return nil
+	}
+	f := auditLog.file
+	auditLog.file = nil
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// accountRole возвращает роль пользователя для журнала аудита
+func accountRole(a Account) string {
+	switch {
+	case a.Role != "":
+		return a.Role
+	case a.Organization != "":
+		return RoleOrganization
+	case a.Student != "":
+		return RoleStudent
+	}
+	return ""
+}
+
+// accountLogin находит логин по GUID организации или СНИЛС студента.
+// Если аккаунта нет, возвращается сам идентификатор.
+func accountLogin(organization, student string) string {
+	login, _, ok := findAccount(func(a Account) bool {
+		return (organization != "" && a.Organization == organization) || (student != "" && a.Student == student)
+	})
+	if ok {
+		return login
+	}
+	if organization != "" {
+		return organization
+	}
+	return student
+}
+
+// snapshot сохраняет состояние объекта на момент записи в журнал
+func snapshot(v any) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// audit добавляет запись в журнал аудита. actor - логин пользователя,
+// before и after - состояние объекта до и после действия (nil, если его не было).
+func audit(r *http.Request, actor, action, target string, before, after any) {
+	// Записи пишутся под dataMu, поэтому роль берется из кэша без запроса к каталогу
+	actorAccount, _ := cachedAccount(actor)
+	e := AuditEntry{
+		Time:   time.Now(),
+		Actor:  actor,
+		Role:   accountRole(actorAccount),
+		Action: action,
+		Target: target,
+		Before: snapshot(before),
+		After:  snapshot(after),
+		IP:     shared.ClientIP(r),
+	}
+
+	auditLog.Lock()
+	defer auditLog.Unlock()
+
+	e.ID = len(auditLog.entries) + 1
+	auditLog.entries = append(auditLog.entries, e)
+	if auditLog.file != nil {
+		// Запись сбрасывается на диск сразу: журнал не должен терять действия при сбое
+		line, _ := json.Marshal(e)
+		if _, err := auditLog.file.Write(append(line, '\n')); err != nil {
+			requestLogger(r).Error("не удалось записать журнал аудита", "error", err)
+		} else if err := auditLog.file.Sync(); err != nil {
+			requestLogger(r).Error("не удалось сбросить журнал аудита на диск", "error", err)
+		}
+	}
+	requestLogger(r).Info("запись аудита", "audit_id", e.ID, "action", e.Action)
+}
+
+// 50. Get Audit Log - GET /JobService/hs/jobservice/auditlog/?actor=Login&action=vacancy.create&target=ID&datebegin=20260101&dateend=20261231&format=csv
+// format=csv выгружает журнал файлом для проверок.
+func getAuditLog(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	q := r.URL.Query()
+	if _, ok := requestAdmin(w, r); !ok {
+		return
+	}
+	var from, to time.Time
+	if s := q.Get("datebegin"); s != "" {
+		if len(s) != 8 {
+			writeError(w, http.StatusBadRequest, "некорректный datebegin")
+			return
+		}
+		from = parseDate(s)
+	}
+	if s := q.Get("dateend"); s != "" {
+		if len(s) != 8 {
+			writeError(w, http.StatusBadRequest, "некорректный dateend")
+			return
+		}
+		to = parseDate(s).AddDate(0, 0, 1)
+	}
+	actor, action, target := q.Get("actor"), q.Get("action"), q.Get("target")
+
+	auditLog.Lock()
+	result := make([]AuditEntry, 0)
+	for _, e := range auditLog.entries {
+		if (actor == "" || e.Actor == actor) &&
+			(action == "" || e.Action == action) &&
+			(target == "" || e.Target == target) &&
+			(from.IsZero() || !e.Time.Before(from)) &&
+			(to.IsZero() || e.Time.Before(to)) {
+			result = append(result, e)
+		}
+	}
+	auditLog.Unlock()
+
+	requestLogger(r).Debug("возвращены записи журнала аудита", "count", len(result))
+
+	if q.Get("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="audit.csv"`)
+		w.WriteHeader(http.StatusOK)
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"ID", "Time", "Actor", "Role", "Action", "Target", "Before", "After", "IP"})
+		for _, e := range result {
+			cw.Write([]string{
+				strconv.Itoa(e.ID), e.Time.Format(time.RFC3339), e.Actor, e.Role,
+				e.Action, e.Target, string(e.Before), string(e.After), e.IP,
+			})
+		}
+		cw.Flush()
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
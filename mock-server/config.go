@@ -0,0 +1,240 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"shared"
+)
+
+// Настройки читаются через shared.LoadSettings: флаги, переменные окружения,
+// файл настроек и значения по умолчанию.
+
+// Config - настройки mock-server
+type Config struct {
+	shared.ServerConfig
+	CORS CORSPolicy
+	// CSRF - требовать токен X-CSRF-Token в изменяющих запросах
+	CSRF bool
+	OIDC OIDCConfig
+	// DevIdP - запустить встроенный поставщик удостоверений (только для разработки)
+	DevIdP bool
+	// LDAP - каталог учетных записей, пустой URL - встроенные учетные записи
+	LDAP LDAPConfig
+	// DevLDAP - адрес встроенного каталога LDAP (только для разработки)
+	DevLDAP string
+	// RateLimits - ограничения частоты запросов по именам маршрутов
+	RateLimits map[string]RateLimit
+	// Storage - каталог журнала аудита и вложений (в настройках - file:каталог)
+	Storage  string
+	SeedFile string
+}
+
+// rawConfig - настройки в текстовом виде до проверки
+type rawConfig struct {
+	shared.RawServerConfig
+	CORSOrigins, CORSCredentials, CORSMaxAge    string
+	CSRF, RateLimits                            string
+	OIDCIssuer, OIDCClientID, OIDCClientSecret  string
+	OIDCRedirectURL, OIDCPostLoginURL, DevIdP   string
+	LDAPURL, LDAPBindDN, LDAPBindPassword       string
+	LDAPBaseDN, LDAPLoginAttr, LDAPSyncInterval string
+	DevLDAP                                     string
+	Storage, SeedFile                           string
+}
+
+func (c *rawConfig) settings() []shared.Setting {
+	return append(c.RawServerConfig.Settings(), []shared.Setting{
+		{Key: "cors_origins", Env: "CORS_ORIGINS", Usage: "источники, которым разрешены запросы из браузера, через запятую, или *", Value: &c.CORSOrigins},
+		{Key: "cors_credentials", Env: "CORS_CREDENTIALS", Usage: "разрешить браузеру передавать cookie и Authorization", Value: &c.CORSCredentials, Bool: true},
+		{Key: "cors_max_age", Env: "CORS_MAX_AGE", Usage: "сколько секунд браузер хранит ответ на предварительный запрос", Value: &c.CORSMaxAge},
+		{Key: "csrf", Env: "CSRF_PROTECTION", Usage: "требовать CSRF-токен в изменяющих запросах", Value: &c.CSRF, Bool: true},
+		{Key: "oidc_issuer", Env: "OIDC_ISSUER", Usage: "адрес поставщика удостоверений OpenID Connect", Value: &c.OIDCIssuer},
+		{Key: "oidc_client_id", Env: "OIDC_CLIENT_ID", Usage: "идентификатор клиента у поставщика", Value: &c.OIDCClientID},
+		{Key: "oidc_client_secret", Env: "OIDC_CLIENT_SECRET", Usage: "секрет клиента у поставщика", Value: &c.OIDCClientSecret},
+		{Key: "oidc_redirect_url", Env: "OIDC_REDIRECT_URL", Usage: "адрес /sso/callback, зарегистрированный у поставщика", Value: &c.OIDCRedirectURL},
+		{Key: "oidc_post_login_url", Env: "OIDC_POST_LOGIN_URL", Usage: "страница, на которую браузер возвращается после входа", Value: &c.OIDCPostLoginURL},
+		{Key: "dev_idp", Env: "DEV_IDP", Usage: "запустить встроенный поставщик удостоверений (только для разработки)", Value: &c.DevIdP, Bool: true},
+		{Key: "ldap_url", Env: "LDAP_URL", Usage: "каталог учетных записей, ldap://host:389 или ldaps://host:636", Value: &c.LDAPURL},
+		{Key: "ldap_bind_dn", Env: "LDAP_BIND_DN", Usage: "имя для привязки к каталогу", Value: &c.LDAPBindDN},
+		{Key: "ldap_bind_password", Env: "LDAP_BIND_PASSWORD", Usage: "пароль для привязки к каталогу", Value: &c.LDAPBindPassword},
+		{Key: "ldap_base_dn", Env: "LDAP_BASE_DN", Usage: "поддерево каталога с пользователями", Value: &c.LDAPBaseDN},
+		{Key: "ldap_login_attr", Env: "LDAP_LOGIN_ATTR", Usage: "атрибут с логином: uid или sAMAccountName", Value: &c.LDAPLoginAttr},
+		{Key: "ldap_sync_interval", Env: "LDAP_SYNC_INTERVAL", Usage: "период синхронизации с каталогом, 0 - только поиск при входе", Value: &c.LDAPSyncInterval},
+		{Key: "dev_ldap", Env: "DEV_LDAP", Usage: "адрес встроенного каталога LDAP (только для разработки)", Value: &c.DevLDAP},
+		{Key: "rate_limits", Env: "RATE_LIMITS", Usage: "лимиты запросов: маршрут=количество/период через запятую", Value: &c.RateLimits},
+		{Key: "storage", Env: "STORAGE_DSN", Usage: "хранилище журнала аудита и вложений, file:каталог", Value: &c.Storage},
+		{Key: "seed_file", Env: "SEED_FILE", Usage: "JSON-файл с начальными данными вместо встроенных", Value: &c.SeedFile},
+	}...)
+}
+
+var defaultRawConfig = rawConfig{
+	RawServerConfig: shared.RawServerConfig{
+		Listen:     ":80",
+		DevTLS:     "false",
+		HSTSMaxAge: "31536000",
+		LogLevel:   "info",
+		LogOutput:  "stdout",
+	},
+	CORSOrigins:      "*",
+	CORSCredentials:  "false",
+	CORSMaxAge:       "600",
+	CSRF:             "false",
+	RateLimits:       defaultRateLimits,
+	OIDCClientID:     "jobservice",
+	OIDCRedirectURL:  "http://localhost/JobService/hs/jobservice/sso/callback/",
+	OIDCPostLoginURL: "http://localhost:8080/main.html",
+	DevIdP:           "false",
+	LDAPLoginAttr:    "uid",
+	LDAPSyncInterval: "15m",
+	Storage:          "file:.",
+}
+
+// loadConfig собирает настройки из аргументов командной строки, окружения и файла
+func loadConfig(args []string) (Config, error) {
+	raw := defaultRawConfig
+	if err := shared.LoadSettings("mock-server", args, raw.settings()); err != nil {
+		return Config{}, err
+	}
+	return raw.validate()
+}
+
+// validate проверяет настройки и приводит их к рабочему виду
+func (c rawConfig) validate() (Config, error) {
+	server, err := c.RawServerConfig.Validate()
+	if err != nil {
+		return Config{}, err
+	}
+	cfg := Config{ServerConfig: server, SeedFile: c.SeedFile}
+	if c.SeedFile != "" {
+		if _, err := os.Stat(c.SeedFile); err != nil {
+			return Config{}, err
+		}
+	}
+
+	for origin := range strings.SplitSeq(c.CORSOrigins, ",") {
+		origin = strings.TrimSpace(origin)
+		if origin == "" {
+			continue
+		}
+		if origin != "*" {
+			u, err := url.Parse(origin)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+				return Config{}, fmt.Errorf("cors_origins: некорректный источник %q", origin)
+			}
+			origin = u.Scheme + "://" + u.Host
+		}
+		cfg.CORS.Origins = append(cfg.CORS.Origins, origin)
+	}
+	credentials, err := strconv.ParseBool(c.CORSCredentials)
+	if err != nil {
+		return Config{}, fmt.Errorf("cors_credentials: ожидается true или false, получено %q", c.CORSCredentials)
+	}
+	if credentials && slices.Contains(cfg.CORS.Origins, "*") {
+		return Config{}, errors.New("cors_credentials требует явного списка cors_origins вместо *")
+	}
+	cfg.CORS.Credentials = credentials
+	maxAge, err := strconv.Atoi(c.CORSMaxAge)
+	if err != nil || maxAge < 0 {
+		return Config{}, fmt.Errorf("cors_max_age: ожидается число секунд, получено %q", c.CORSMaxAge)
+	}
+	cfg.CORS.MaxAge = time.Duration(maxAge) * time.Second
+	if cfg.CSRF, err = strconv.ParseBool(c.CSRF); err != nil {
+		return Config{}, fmt.Errorf("csrf: ожидается true или false, получено %q", c.CSRF)
+	}
+	// Без cors_credentials браузер не передаст cookie с токеном со страниц
+	// сервера страниц, и все изменяющие запросы будут отклонены
+	if cfg.CSRF && !cfg.CORS.Credentials {
+		return Config{}, errors.New("csrf требует cors_credentials")
+	}
+
+	if cfg.DevIdP, err = strconv.ParseBool(c.DevIdP); err != nil {
+		return Config{}, fmt.Errorf("dev_idp: ожидается true или false, получено %q", c.DevIdP)
+	}
+	cfg.OIDC = OIDCConfig{
+		Issuer:       strings.TrimSuffix(c.OIDCIssuer, "/"),
+		ClientID:     c.OIDCClientID,
+		ClientSecret: c.OIDCClientSecret,
+		RedirectURL:  c.OIDCRedirectURL,
+		PostLoginURL: c.OIDCPostLoginURL,
+	}
+	if cfg.DevIdP || cfg.OIDC.Issuer != "" {
+		for key, value := range map[string]string{"oidc_redirect_url": c.OIDCRedirectURL, "oidc_post_login_url": c.OIDCPostLoginURL} {
+			if u, err := url.Parse(value); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return Config{}, fmt.Errorf("%s: некорректный адрес %q", key, value)
+			}
+		}
+		// Встроенный поставщик работает на этом же сервере, по адресу
+		// oidc_redirect_url он доступен браузеру
+		if cfg.DevIdP && cfg.OIDC.Issuer == "" {
+			u, _ := url.Parse(c.OIDCRedirectURL)
+			cfg.OIDC.Issuer = u.Scheme + "://" + u.Host + "/devidp"
+		}
+		if u, err := url.Parse(cfg.OIDC.Issuer); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return Config{}, fmt.Errorf("oidc_issuer: некорректный адрес %q", c.OIDCIssuer)
+		}
+		if cfg.OIDC.ClientID == "" {
+			return Config{}, errors.New("oidc_client_id не задан")
+		}
+		// Страницы узнают пользователя по cookie сессии
+		if !cfg.CORS.Credentials {
+			return Config{}, errors.New("вход через поставщика удостоверений требует cors_credentials")
+		}
+	}
+
+	cfg.DevLDAP = c.DevLDAP
+	cfg.LDAP = LDAPConfig{
+		URL:          c.LDAPURL,
+		BindDN:       c.LDAPBindDN,
+		BindPassword: c.LDAPBindPassword,
+		BaseDN:       c.LDAPBaseDN,
+		LoginAttr:    c.LDAPLoginAttr,
+	}
+	if cfg.DevLDAP != "" {
+		if _, _, err := net.SplitHostPort(cfg.DevLDAP); err != nil {
+			return Config{}, fmt.Errorf("dev_ldap: некорректный адрес %q", cfg.DevLDAP)
+		}
+		if cfg.LDAP.URL == "" {
+			cfg.LDAP.URL = "ldap://" + cfg.DevLDAP
+		}
+		if cfg.LDAP.BaseDN == "" {
+			cfg.LDAP.BaseDN = devLDAPBaseDN
+		}
+	}
+	if cfg.LDAP.URL != "" {
+		u, err := url.Parse(cfg.LDAP.URL)
+		if err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") || u.Host == "" {
+			return Config{}, fmt.Errorf("ldap_url: ожидается ldap://host:port или ldaps://host:port, получено %q", cfg.LDAP.URL)
+		}
+		if cfg.LDAP.BaseDN == "" {
+			return Config{}, errors.New("ldap_base_dn не задан")
+		}
+		if cfg.LDAP.LoginAttr == "" {
+			return Config{}, errors.New("ldap_login_attr не задан")
+		}
+		interval, err := time.ParseDuration(c.LDAPSyncInterval)
+		if err != nil || interval < 0 {
+			return Config{}, fmt.Errorf("ldap_sync_interval: ожидается период вида 15m, получено %q", c.LDAPSyncInterval)
+		}
+		cfg.LDAP.SyncInterval = interval
+	}
+
+	if cfg.RateLimits, err = parseRateLimits(c.RateLimits); err != nil {
+		return Config{}, fmt.Errorf("rate_limits: %v", err)
+	}
+
+	dir, ok := strings.CutPrefix(c.Storage, "file:")
+	if !ok || dir == "" {
+		return Config{}, fmt.Errorf("storage: поддерживается только file:каталог, получено %q", c.Storage)
+	}
+	cfg.Storage = filepath.Clean(dir)
+	return cfg, nil
+}
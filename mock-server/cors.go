@@ -0,0 +1,106 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSPolicy - правила доступа к API со страниц других источников
+type CORSPolicy struct {
+	// Origins - разрешенные источники вида https://host:port, "*" - любые
+	Origins []string
+	// Credentials разрешает браузеру передавать cookie и заголовок Authorization.
+	// Несовместимо с Origins = "*".
+	Credentials bool
+	// MaxAge - сколько браузер может не повторять предварительный запрос
+	MaxAge time.Duration
+}
+
+var corsPolicy = CORSPolicy{Origins: []string{"*"}, MaxAge: 10 * time.Minute}
+
+// Методы, которые проверяются при ответе на предварительный запрос
+var corsMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+const (
+	corsAllowedHeaders = "Content-Type, Authorization, X-Request-ID, X-CSRF-Token"
+	// Заголовки ответа, доступные скриптам страницы: идентификатор запроса,
+	// число записей в списке и имя выгружаемого файла
+	corsExposedHeaders = "X-Request-ID, X-Total-Count, Content-Disposition"
+)
+
+// allowOrigin возвращает значение Access-Control-Allow-Origin для источника
+func (p CORSPolicy) allowOrigin(origin string) (string, bool) {
+	if slices.Contains(p.Origins, "*") {
+		return "*", true
+	}
+	if origin != "" && slices.Contains(p.Origins, origin) {
+		return origin, true
+	}
+	return "", false
+}
+
+// routeMethods возвращает методы, зарегистрированные в mux для пути запроса
+func routeMethods(mux *http.ServeMux, r *http.Request) []string {
+	var methods []string
+	for _, m := range corsMethods {
+		probe := r.Clone(r.Context())
+		probe.Method = m
+		if _, pattern := mux.Handler(probe); pattern != "" {
+			methods = append(methods, m)
+		}
+	}
+	return methods
+}
+
+// corsMiddleware добавляет заголовки CORS по corsPolicy и отвечает на
+// предварительные запросы браузера. Разрешенные методы берутся из шаблонов
+// маршрутов mux, поэтому для каждого пути они свои.
+func corsMiddleware(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		allowed, ok := corsPolicy.allowOrigin(r.Header.Get("Origin"))
+
+		requested := r.Header.Get("Access-Control-Request-Method")
+		if r.Method != http.MethodOptions || requested == "" {
+			if ok {
+				h.Set("Access-Control-Allow-Origin", allowed)
+				h.Set("Access-Control-Expose-Headers", corsExposedHeaders)
+				if corsPolicy.Credentials {
+					h.Set("Access-Control-Allow-Credentials", "true")
+				}
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Предварительный запрос
+		h.Add("Vary", "Access-Control-Request-Method")
+		h.Add("Vary", "Access-Control-Request-Headers")
+		if !ok {
+			writeError(w, http.StatusForbidden, "источник не разрешен")
+			return
+		}
+		methods := routeMethods(mux, r)
+		if len(methods) == 0 {
+			writeError(w, http.StatusNotFound, "маршрут не найден")
+			return
+		}
+		h.Set("Access-Control-Allow-Origin", allowed)
+		if corsPolicy.Credentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+		if !slices.Contains(methods, requested) {
+			h.Set("Allow", strings.Join(methods, ", "))
+			writeError(w, http.StatusMethodNotAllowed, "метод не поддерживается")
+			return
+		}
+		h.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+		h.Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+		h.Set("Access-Control-Max-Age", strconv.Itoa(int(corsPolicy.MaxAge.Seconds())))
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+)
+
+// Защита от CSRF по схеме double-submit: сервер выдает токен в cookie и в
+// теле ответа, а страница передает его в заголовке X-CSRF-Token. Чужой сайт
+// может заставить браузер отправить cookie, но не может прочитать токен,
+// поэтому заголовок с тем же значением подставить не сможет.
+
+const (
+	csrfCookie = "csrf_token"
+	csrfHeader = "X-CSRF-Token"
+)
+
+// csrfProtection включает проверку токена для изменяющих запросов
+var csrfProtection bool
+
+// randomToken возвращает случайный токен из 32 байт в шестнадцатеричной записи
+func randomToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validCSRFToken проверяет, что токен выдан этим сервером по формату
+func validCSRFToken(token string) bool {
+	b, err := hex.DecodeString(token)
+	return err == nil && len(b) == 32
+}
+
+// csrfMiddleware отклоняет изменяющие запросы без токена в заголовке или с
+// токеном, не совпадающим с cookie
+func csrfMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !csrfProtection {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cookie, err := r.Cookie(csrfCookie)
+		header := r.Header.Get(csrfHeader)
+		if err != nil || header == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(header)) != 1 {
+			requestLogger(r).Warn("отклонен запрос без действительного CSRF-токена", "cookie", err == nil, "header", header != "")
+			writeError(w, http.StatusForbidden, "недействительный CSRF-токен")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// 51. CSRF Token - GET /JobService/hs/jobservice/csrftoken
+// Возвращает токен из cookie, а если его нет - выдает новый.
+// Cookie недоступна скриптам, токен страница берет из ответа.
+func csrfToken(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	token := ""
+	if c, err := r.Cookie(csrfCookie); err == nil && validCSRFToken(c.Value) {
+		token = c.Value
+	} else {
+		token = randomToken()
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookie,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	w.Header().Set("Cache-Control", "no-store")
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{"token": token, "enabled": csrfProtection})
+}
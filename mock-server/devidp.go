@@ -0,0 +1,323 @@
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"html/template"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Встроенный поставщик удостоверений (dev_idp) заменяет «Мой Универ» при
+// разработке без доступа к сети университета. Он пускает пользователей
+// платформы с общим паролем devIdPPassword и выдает ID-токены с их ролями.
+// Ключ подписи создается при запуске и нигде не сохраняется.
+
+const (
+	devIdPPassword  = "password"
+	devIdPCodeTTL   = time.Minute
+	devIdPTokenTTL  = 5 * time.Minute
+	devIdPKeyBits   = 2048
+	devIdPTokenType = "Bearer"
+)
+
+type devIdPCode struct {
+	login         string
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+	expires       time.Time
+}
+
+type devIdP struct {
+	cfg  OIDCConfig
+	path string
+	key  *rsa.PrivateKey
+	kid  string
+
+	mu    sync.Mutex
+	codes map[string]devIdPCode
+}
+
+func newDevIdP(cfg OIDCConfig) (*devIdP, error) {
+	u, err := url.Parse(cfg.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	key, err := rsa.GenerateKey(rand.Reader, devIdPKeyBits)
+	if err != nil {
+		return nil, err
+	}
+	return &devIdP{
+		cfg:   cfg,
+		path:  strings.TrimSuffix(u.Path, "/"),
+		key:   key,
+		kid:   randomToken()[:16],
+		codes: map[string]devIdPCode{},
+	}, nil
+}
+
+// handler возвращает маршруты поставщика. Они обслуживаются отдельно от API:
+// у поставщика свои форматы ответов и своя защита запросов.
+func (p *devIdP) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+p.path+"/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("GET "+p.path+"/jwks", p.jwks)
+	mux.HandleFunc("GET "+p.path+"/authorize", p.authorizeForm)
+	mux.HandleFunc("POST "+p.path+"/authorize", p.authorize)
+	mux.HandleFunc("POST "+p.path+"/token", p.token)
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (p *devIdP) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                p.cfg.Issuer,
+		"authorization_endpoint":                p.cfg.Issuer + "/authorize",
+		"token_endpoint":                        p.cfg.Issuer + "/token",
+		"jwks_uri":                              p.cfg.Issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"scopes_supported":                      []string{"openid", "profile"},
+		"claims_supported":                      []string{"sub", "preferred_username", "student_id", "organization", "role"},
+	})
+}
+
+func (p *devIdP) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := p.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"use": "sig",
+		"alg": "RS256",
+		"kid": p.kid,
+		"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}}})
+}
+
+var devIdPLoginPage = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head><meta charset="utf-8"><title>Вход — тестовый «Мой Универ»</title></head>
+<body style="font-family: sans-serif; max-width: 360px; margin: 60px auto;">
+<h2>Тестовый «Мой Универ»</h2>
+<p>Пароль для всех пользователей: <code>{{.Password}}</code></p>
+{{if .Error}}<p style="color: red;">{{.Error}}</p>{{end}}
+<form method="post">
+{{range $k, $v := .Params}}<input type="hidden" name="{{$k}}" value="{{$v}}">
+{{end}}<p><input name="login" placeholder="Логин" value="{{.Login}}" required autofocus></p>
+<p><input name="password" type="password" placeholder="Пароль" required></p>
+<p><button type="submit">Войти</button></p>
+</form>
+</body>
+</html>
+`))
+
+// Параметры запроса авторизации, которые форма входа передает дальше
+var devIdPAuthParams = []string{"response_type", "client_id", "redirect_uri", "scope", "state", "nonce", "code_challenge", "code_challenge_method"}
+
+func (p *devIdP) renderLogin(w http.ResponseWriter, params url.Values, login, message string) {
+	hidden := map[string]string{}
+	for _, k := range devIdPAuthParams {
+		hidden[k] = params.Get(k)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	if message != "" {
+		w.WriteHeader(http.StatusUnauthorized)
+	}
+	devIdPLoginPage.Execute(w, map[string]any{
+		"Password": devIdPPassword, "Params": hidden, "Login": login, "Error": message,
+	})
+}
+
+// checkAuthRequest проверяет клиента и адрес возврата. С неверным адресом
+// пользователя нельзя вернуть к клиенту, поэтому ошибка показывается сразу.
+func (p *devIdP) checkAuthRequest(w http.ResponseWriter, q url.Values) bool {
+	switch {
+	case q.Get("client_id") != p.cfg.ClientID:
+		http.Error(w, "неизвестный client_id", http.StatusBadRequest)
+		return false
+	case q.Get("redirect_uri") != p.cfg.RedirectURL:
+		http.Error(w, "redirect_uri не зарегистрирован", http.StatusBadRequest)
+		return false
+	case q.Get("response_type") != "code":
+		http.Error(w, "поддерживается только response_type=code", http.StatusBadRequest)
+		return false
+	case q.Get("code_challenge") == "" || q.Get("code_challenge_method") != "S256":
+		http.Error(w, "требуется PKCE с методом S256", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// GET /devidp/authorize - форма входа
+func (p *devIdP) authorizeForm(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if !p.checkAuthRequest(w, q) {
+		return
+	}
+	p.renderLogin(w, q, "", "")
+}
+
+// POST /devidp/authorize - проверка логина и пароля, выдача кода
+func (p *devIdP) authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "некорректная форма", http.StatusBadRequest)
+		return
+	}
+	form := r.PostForm
+	if !p.checkAuthRequest(w, form) {
+		return
+	}
+	login := strings.TrimSpace(form.Get("login"))
+	_, exists := lookupAccount(r.Context(), login)
+	if !exists || subtle.ConstantTimeCompare([]byte(form.Get("password")), []byte(devIdPPassword)) != 1 {
+		p.renderLogin(w, form, login, "Неверный логин или пароль")
+		return
+	}
+
+	code := randomToken()
+	now := time.Now()
+	p.mu.Lock()
+	for c, v := range p.codes {
+		if now.After(v.expires) {
+			delete(p.codes, c)
+		}
+	}
+	p.codes[code] = devIdPCode{
+		login:         login,
+		redirectURI:   form.Get("redirect_uri"),
+		nonce:         form.Get("nonce"),
+		codeChallenge: form.Get("code_challenge"),
+		expires:       now.Add(devIdPCodeTTL),
+	}
+	p.mu.Unlock()
+
+	u, _ := url.Parse(form.Get("redirect_uri"))
+	q := u.Query()
+	q.Set("code", code)
+	if state := form.Get("state"); state != "" {
+		q.Set("state", state)
+	}
+	u.RawQuery = q.Encode()
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+// tokenError отвечает ошибкой в формате OAuth 2.0
+func tokenError(w http.ResponseWriter, status int, code, description string) {
+	writeJSON(w, status, map[string]string{"error": code, "error_description": description})
+}
+
+// POST /devidp/token - обмен кода на ID-токен
+func (p *devIdP) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, http.StatusBadRequest, "invalid_request", "некорректная форма")
+		return
+	}
+	form := r.PostForm
+	clientID, secret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID, secret = form.Get("client_id"), form.Get("client_secret")
+	}
+	if clientID != p.cfg.ClientID || subtle.ConstantTimeCompare([]byte(secret), []byte(p.cfg.ClientSecret)) != 1 {
+		tokenError(w, http.StatusUnauthorized, "invalid_client", "неверный клиент или секрет")
+		return
+	}
+	if form.Get("grant_type") != "authorization_code" {
+		tokenError(w, http.StatusBadRequest, "unsupported_grant_type", "поддерживается только authorization_code")
+		return
+	}
+
+	p.mu.Lock()
+	c, ok := p.codes[form.Get("code")]
+	// Код одноразовый, даже если обмен не удастся
+	delete(p.codes, form.Get("code"))
+	p.mu.Unlock()
+	now := time.Now()
+	switch {
+	case !ok || now.After(c.expires):
+		tokenError(w, http.StatusBadRequest, "invalid_grant", "код не найден или устарел")
+		return
+	case form.Get("redirect_uri") != c.redirectURI:
+		tokenError(w, http.StatusBadRequest, "invalid_grant", "redirect_uri не совпадает")
+		return
+	case pkceChallenge(form.Get("code_verifier")) != c.codeChallenge:
+		tokenError(w, http.StatusBadRequest, "invalid_grant", "code_verifier не совпадает")
+		return
+	}
+
+	account, _ := lookupAccount(r.Context(), c.login)
+	claims := map[string]any{
+		"iss":                p.cfg.Issuer,
+		"sub":                c.login,
+		"aud":                p.cfg.ClientID,
+		"iat":                now.Unix(),
+		"exp":                now.Add(devIdPTokenTTL).Unix(),
+		"preferred_username": c.login,
+	}
+	if c.nonce != "" {
+		claims["nonce"] = c.nonce
+	}
+	if account.Student != "" {
+		claims["student_id"] = account.Student
+	}
+	if account.Organization != "" {
+		claims["organization"] = account.Organization
+	}
+	if account.Role != "" {
+		claims["role"] = account.Role
+	}
+	idToken, err := p.sign(claims)
+	if err != nil {
+		slog.Error("встроенный поставщик не смог подписать токен", "error", err)
+		tokenError(w, http.StatusInternalServerError, "server_error", "не удалось подписать токен")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": randomToken(),
+		"token_type":   devIdPTokenType,
+		"expires_in":   int(devIdPTokenTTL.Seconds()),
+		"id_token":     idToken,
+	})
+}
+
+// sign подписывает утверждения в JWT с алгоритмом RS256
+func (p *devIdP) sign(claims map[string]any) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": p.kid})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"log/slog"
+	"maps"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Встроенный каталог LDAP (dev_ldap) заменяет каталог университета при
+// разработке. Он отдает учетные записи, загруженные при запуске (встроенные
+// или из seed_file), в том виде, в каком их хранит каталог: логин в uid и
+// sAMAccountName, роли сотрудников - группами в memberOf. Поддерживаются
+// простая привязка, поиск по фильтрам равенства и наличия атрибута и
+// их комбинациям через И, ИЛИ, НЕ, а также постраничная выдача.
+
+const devLDAPBaseDN = "dc=dvfu,dc=ru"
+
+// devLDAPDirectory строит записи каталога из учетных записей
+func devLDAPDirectory(accounts map[string]Account) []ldapEntry {
+	groupDN := map[string]string{}
+	for _, g := range ldapRoleGroups {
+		groupDN[g.role] = "cn=" + g.cn + ",ou=groups," + devLDAPBaseDN
+	}
+	entries := make([]ldapEntry, 0, len(accounts))
+	for _, login := range slices.Sorted(maps.Keys(accounts)) {
+		a := accounts[login]
+		attrs := map[string][]string{
+			"objectclass":    {"top", "person", "organizationalPerson", "inetOrgPerson"},
+			"uid":            {login},
+			"samaccountname": {login},
+		}
+		if a.Student != "" {
+			attrs[strings.ToLower(ldapStudentAttr)] = []string{a.Student}
+		}
+		if a.Organization != "" {
+			attrs[strings.ToLower(ldapOrganizationAttr)] = []string{a.Organization}
+		}
+		groups := slices.Clone(a.Groups)
+		if dn, ok := groupDN[a.Role]; ok && !slices.Contains(groups, dn) {
+			groups = append(groups, dn)
+		}
+		if len(groups) > 0 {
+			attrs[strings.ToLower(ldapGroupsAttr)] = groups
+		}
+		entries = append(entries, ldapEntry{dn: "uid=" + login + ",ou=people," + devLDAPBaseDN, attrs: attrs})
+	}
+	return entries
+}
+
+// devLDAP - встроенный каталог. Если BindDN задан, поиск доступен только
+// после привязки с этим именем и паролем.
+type devLDAP struct {
+	entries      []ldapEntry
+	bindDN       string
+	bindPassword string
+	// pageSize ограничивает размер страницы постраничной выдачи, как
+	// MaxPageSize в Active Directory; 0 - страница того размера, что просит клиент
+	pageSize int
+}
+
+// serveDevLDAP принимает соединения на addr до закрытия слушателя
+func serveDevLDAP(addr string, d *devLDAP) (net.Listener, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					slog.Error("встроенный каталог LDAP остановлен", "error", err)
+				}
+				return
+			}
+			go d.serve(conn)
+		}
+	}()
+	return ln, nil
+}
+
+func (d *devLDAP) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	bound := d.bindDN == ""
+	for {
+		data, err := readBER(r)
+		if err != nil {
+			return
+		}
+		msg, _, err := parseBER(data)
+		if err != nil {
+			return
+		}
+		parts, err := msg.children()
+		if err != nil || len(parts) < 2 {
+			return
+		}
+		id, op := parts[0].int(), parts[1]
+		message := func(op []byte, controls ...[]byte) []byte {
+			fields := [][]byte{berInt(berInteger, id), op}
+			if len(controls) > 0 {
+				fields = append(fields, berSeq(ldapControls, controls...))
+			}
+			return berSeq(berSequence, fields...)
+		}
+		reply := func(msgs ...[]byte) error {
+			for _, m := range msgs {
+				if _, err := conn.Write(m); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		switch op.tag {
+		case ldapBindRequest:
+			code := ldapResultSuccess
+			fields, err := op.children()
+			if err != nil || len(fields) < 3 {
+				return
+			}
+			if d.bindDN != "" {
+				bound = strings.EqualFold(fields[1].str(), d.bindDN) && fields[2].str() == d.bindPassword
+				if !bound {
+					code = ldapResultBadCreds
+				}
+			}
+			if reply(message(ldapResultOp(ldapBindResponse, code, ""))) != nil {
+				return
+			}
+		case ldapSearchRequest:
+			if !bound {
+				// insufficientAccessRights
+				reply(message(ldapResultOp(ldapSearchDone, 50, "требуется привязка")))
+				continue
+			}
+			fields, err := op.children()
+			if err != nil || len(fields) < 8 {
+				return
+			}
+			base, limit, filter := strings.ToLower(fields[0].str()), fields[3].int(), fields[6]
+			var found []ldapEntry
+			for _, e := range d.entries {
+				if limit > 0 && len(found) >= limit {
+					break
+				}
+				if strings.HasSuffix(strings.ToLower(e.dn), base) && devLDAPMatch(filter, e) {
+					found = append(found, e)
+				}
+			}
+			var controls [][]byte
+			if size, cookie, ok := devLDAPPaging(parts[2:]); ok {
+				if d.pageSize > 0 && (size <= 0 || size > d.pageSize) {
+					size = d.pageSize
+				}
+				// Признак следующей страницы - номер первой записи на ней
+				offset, _ := strconv.Atoi(cookie)
+				found = found[min(offset, len(found)):]
+				next := ""
+				if size > 0 && size < len(found) {
+					found = found[:size]
+					next = strconv.Itoa(offset + size)
+				}
+				controls = append(controls, devLDAPPagingControl(next))
+			}
+			var msgs [][]byte
+			for _, e := range found {
+				msgs = append(msgs, message(devLDAPEntryOp(e)))
+			}
+			msgs = append(msgs, message(ldapResultOp(ldapSearchDone, ldapResultSuccess, ""), controls...))
+			if reply(msgs...) != nil {
+				return
+			}
+		case ldapUnbindRequest:
+			return
+		default:
+			// Остальные операции каталогу разработки не нужны
+			slog.Warn("встроенный каталог LDAP: неподдерживаемая операция", "op", op.tag)
+			return
+		}
+	}
+}
+
+// devLDAPPaging находит среди элементов управления запроса постраничную
+// выдачу и возвращает размер страницы и признак продолжения
+func devLDAPPaging(rest []berElement) (int, string, bool) {
+	if len(rest) == 0 || rest[0].tag != ldapControls {
+		return 0, "", false
+	}
+	controls, err := rest[0].children()
+	if err != nil {
+		return 0, "", false
+	}
+	for _, c := range controls {
+		parts, err := c.children()
+		if err != nil || len(parts) < 2 || parts[0].str() != ldapPagedResultsOID {
+			continue
+		}
+		v, _, err := parseBER(parts[len(parts)-1].data)
+		if err != nil {
+			return 0, "", false
+		}
+		fields, err := v.children()
+		if err != nil || len(fields) < 2 {
+			return 0, "", false
+		}
+		return fields[0].int(), fields[1].str(), true
+	}
+	return 0, "", false
+}
+
+func devLDAPPagingControl(cookie string) []byte {
+	return berSeq(berSequence,
+		berString(berOctetString, ldapPagedResultsOID),
+		berTLV(berOctetString, berSeq(berSequence, berInt(berInteger, 0), berString(berOctetString, cookie))))
+}
+
+func ldapResultOp(tag byte, code int, message string) []byte {
+	return berSeq(tag, berInt(berEnumerated, code), berString(berOctetString, ""), berString(berOctetString, message))
+}
+
+func devLDAPEntryOp(e ldapEntry) []byte {
+	var attrs [][]byte
+	for _, name := range slices.Sorted(maps.Keys(e.attrs)) {
+		var vals [][]byte
+		for _, v := range e.attrs[name] {
+			vals = append(vals, berString(berOctetString, v))
+		}
+		attrs = append(attrs, berSeq(berSequence, berString(berOctetString, name), berSeq(berSet, vals...)))
+	}
+	return berSeq(ldapSearchEntry, berString(berOctetString, e.dn), berSeq(berSequence, attrs...))
+}
+
+// devLDAPMatch проверяет запись фильтром поиска
+func devLDAPMatch(f berElement, e ldapEntry) bool {
+	switch f.tag {
+	case ldapFilterAnd, ldapFilterOr:
+		subs, err := f.children()
+		if err != nil {
+			return false
+		}
+		for _, s := range subs {
+			if devLDAPMatch(s, e) != (f.tag == ldapFilterAnd) {
+				return f.tag != ldapFilterAnd
+			}
+		}
+		return f.tag == ldapFilterAnd
+	case ldapFilterNot:
+		sub, _, err := parseBER(f.data)
+		return err == nil && !devLDAPMatch(sub, e)
+	case ldapFilterEquality:
+		fields, err := f.children()
+		if err != nil || len(fields) < 2 {
+			return false
+		}
+		return slices.ContainsFunc(e.attrs[strings.ToLower(fields[0].str())], func(v string) bool {
+			return strings.EqualFold(v, fields[1].str())
+		})
+	case ldapFilterPresent:
+		return len(e.attrs[strings.ToLower(f.str())]) > 0
+	}
+	return false
+}
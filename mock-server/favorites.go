@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// Favorite - вакансия в избранном студента
+type Favorite struct {
+	Number string    `json:"Number"`
+	Added  time.Time `json:"Added"`
+}
+
+// Состояние вакансии из избранного на момент запроса
+const (
+	FavoriteOpen    = "open"
+	FavoriteExpired = "expired"
+	FavoriteClosed  = "closed"
+)
+
+const maxFavoritesPerStudent = 100
+
+// favorites - избранное по идентификатору студента, в порядке добавления
+var favorites = map[string][]Favorite{}
+
+// closedVacancies - вакансии, закрытые работодателем, по номеру.
+// Нужны, чтобы показывать их в избранном после удаления из общего списка.
+var closedVacancies = map[string]Vacancy{}
+
+// favoriteStatus находит актуальные данные вакансии и ее состояние
+func favoriteStatus(number string, now time.Time) (*Vacancy, string) {
+	if v, ok := findVacancy(number); ok {
+		if isOpenVacancy(v, now) {
+			return &v, FavoriteOpen
+		}
+		return &v, FavoriteExpired
+	}
+	if v, ok := closedVacancies[number]; ok {
+		return &v, FavoriteClosed
+	}
+	return nil, FavoriteClosed
+}
+
+// 26. Get Favorites - GET /JobService/hs/jobservice/favorites/?student=Student
+func getFavorites(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	student, ok := requestStudent(w, r, r.URL.Query().Get("student"))
+	if !ok {
+		return
+	}
+	if !isStudent(student) {
+		writeError(w, http.StatusForbidden, "избранное доступно только студентам")
+		return
+	}
+
+	type favoriteItem struct {
+		Favorite
+		Status  string   `json:"Status"`
+		Vacancy *Vacancy `json:"Vacancy"`
+	}
+
+	dataMu.RLock()
+	now := time.Now()
+	result := make([]favoriteItem, 0, len(favorites[student]))
+	for _, f := range favorites[student] {
+		v, status := favoriteStatus(f.Number, now)
+		result = append(result, favoriteItem{Favorite: f, Status: status, Vacancy: v})
+	}
+	dataMu.RUnlock()
+
+	requestLogger(r).Debug("возвращено избранное", "count", len(result))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// 27. Add Favorite - POST /JobService/hs/jobservice/favorite/?student=Student&number=Number
+func addFavorite(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	student, ok := requestStudent(w, r, r.URL.Query().Get("student"))
+	if !ok {
+		return
+	}
+	number := r.URL.Query().Get("number")
+	if !isStudent(student) {
+		writeError(w, http.StatusForbidden, "избранное доступно только студентам")
+		return
+	}
+
+	dataMu.Lock()
+	defer dataMu.Unlock()
+
+	if _, ok := findVacancy(number); !ok {
+		writeError(w, http.StatusNotFound, "вакансия не найдена")
+		return
+	}
+	list := favorites[student]
+	if slices.ContainsFunc(list, func(f Favorite) bool { return f.Number == number }) {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if len(list) >= maxFavoritesPerStudent {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("в избранном не может быть больше %d вакансий", maxFavoritesPerStudent))
+		return
+	}
+	f := Favorite{Number: number, Added: time.Now()}
+	favorites[student] = append(list, f)
+	audit(r, accountLogin("", student), AuditFavoriteAdd, number, nil, f)
+
+	requestLogger(r).Info("вакансия добавлена в избранное", "vacancy", number)
+	w.WriteHeader(http.StatusOK)
+}
+
+// 28. Delete Favorite - POST /JobService/hs/jobservice/deletefavorite/?student=Student&number=Number
+func deleteFavorite(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	student, ok := requestStudent(w, r, r.URL.Query().Get("student"))
+	if !ok {
+		return
+	}
+	number := r.URL.Query().Get("number")
+
+	dataMu.Lock()
+	defer dataMu.Unlock()
+
+	list := favorites[student]
+	i := slices.IndexFunc(list, func(f Favorite) bool { return f.Number == number })
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "вакансии нет в избранном")
+		return
+	}
+	before := list[i]
+	favorites[student] = slices.Delete(list, i, i+1)
+	audit(r, accountLogin("", student), AuditFavoriteDelete, number, before, nil)
+
+	requestLogger(r).Info("вакансия удалена из избранного", "vacancy", number)
+	w.WriteHeader(http.StatusOK)
+}
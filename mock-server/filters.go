@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VacancyFilter - набор фильтров /vacancylist. Те же параметры сохраняются в поисковых подписках.
+type VacancyFilter struct {
+	SalaryMin    int
+	TypesOfWork  []string
+	Organization string
+	DateBegin    time.Time
+	DateEnd      time.Time
+	Query        string
+}
+
+// Параметры /vacancylist, которые понимает VacancyFilter
+var vacancyFilterParams = []string{"salaryMIN", "typesofwork", "organization", "datebegin", "dateend", "q"}
+
+// parseVacancyFilter разбирает параметры запроса /vacancylist.
+// Даты передаются в формате ГГГГММДД, направления работ - через запятую.
+func parseVacancyFilter(q url.Values) (VacancyFilter, error) {
+	var f VacancyFilter
+	if s := q.Get("salaryMIN"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return f, fmt.Errorf("некорректная минимальная зарплата: %s", s)
+		}
+		f.SalaryMin = n
+	}
+	if s := q.Get("typesofwork"); s != "" {
+		f.TypesOfWork = splitTypesOfWork(s)
+	}
+	f.Organization = q.Get("organization")
+	f.Query = strings.TrimSpace(q.Get("q"))
+	for _, d := range []struct {
+		param string
+		dst   *time.Time
+	}{{"datebegin", &f.DateBegin}, {"dateend", &f.DateEnd}} {
+		s := q.Get(d.param)
+		if s == "" {
+			continue
+		}
+		t, err := time.Parse("20060102", s)
+		if err != nil {
+			return f, fmt.Errorf("некорректная дата %s: %s", d.param, s)
+		}
+		*d.dst = t
+	}
+	if !f.DateBegin.IsZero() && !f.DateEnd.IsZero() && f.DateBegin.After(f.DateEnd) {
+		return f, fmt.Errorf("дата начала не может быть позже даты окончания")
+	}
+	return f, nil
+}
+
+// Match сообщает, подходит ли вакансия под фильтр. Из нескольких направлений
+// работ достаточно совпадения хотя бы одного. Поисковый запрос Query здесь
+// не проверяется, его учитывает Apply.
+func (f VacancyFilter) Match(v Vacancy) bool {
+	if v.Salary < f.SalaryMin {
+		return false
+	}
+	if f.Organization != "" && v.OrganizationID != f.Organization {
+		return false
+	}
+	if len(f.TypesOfWork) > 0 && !slices.ContainsFunc(f.TypesOfWork, func(t string) bool {
+		return slices.Contains(v.TypesOfWork, t)
+	}) {
+		return false
+	}
+	if !f.DateBegin.IsZero() && v.DateOfBegin.Before(f.DateBegin) {
+		return false
+	}
+	if !f.DateEnd.IsZero() && v.DateOfEnd.After(f.DateEnd) {
+		return false
+	}
+	return true
+}
+
+// Apply отбирает подходящие вакансии. Направления работ приводятся к справочнику,
+// категория включает все вложенные теги. Если задан поисковый запрос, остаются
+// только найденные вакансии в порядке убывания релевантности.
+// Вызывающий держит dataMu.
+func (f VacancyFilter) Apply(list []Vacancy) []Vacancy {
+	f.TypesOfWork = expandTypesOfWork(f.TypesOfWork)
+
+	result := make([]Vacancy, 0, len(list))
+	for _, v := range list {
+		if f.Match(v) {
+			result = append(result, v)
+		}
+	}
+	if f.Query == "" {
+		return result
+	}
+
+	rank := map[string]int{}
+	for i, hit := range vacancyIndex.Search(f.Query) {
+		rank[hit.Number] = i
+	}
+	found := result[:0]
+	for _, v := range result {
+		if _, ok := rank[v.Number]; ok {
+			found = append(found, v)
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool { return rank[found[i].Number] < rank[found[j].Number] })
+	return found
+}
+
+// expandTypesOfWork приводит теги фильтра к справочнику и добавляет вложенные теги.
+// Неизвестные теги остаются как есть. Вызывающий держит dataMu.
+func expandTypesOfWork(list []string) []string {
+	var result []string
+	for _, t := range list {
+		name, ok := canonicalTag(t)
+		if !ok {
+			result = append(result, t)
+			continue
+		}
+		for _, n := range tagWithDescendants(name) {
+			if !slices.Contains(result, n) {
+				result = append(result, n)
+			}
+		}
+	}
+	return result
+}
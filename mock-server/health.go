@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"shared"
+)
+
+// Проверки состояния отдает shared, здесь - проверки готовности mock-server.
+
+// buildTime задается при сборке: -ldflags "-X main.buildTime=2026-01-01T00:00:00Z"
+var buildTime string
+
+// checkAuditLog проверяет, что файл журнала аудита открыт и доступен
+func checkAuditLog() error {
+	auditLog.Lock()
+	defer auditLog.Unlock()
+	if auditLog.file == nil {
+		return fmt.Errorf("журнал аудита не открыт")
+	}
+	if _, err := auditLog.file.Stat(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// checkBlobStore проверяет, что в хранилище вложений можно записать файл
+func checkBlobStore() error {
+	s, ok := blobStore.(FSBlobStore)
+	if !ok {
+		return nil
+	}
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(s.Dir, ".readyz-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+var readinessChecks = []shared.ReadinessCheck{
+	{Name: "audit_log", Check: checkAuditLog},
+	{Name: "blob_store", Check: checkBlobStore},
+	{Name: "accounts", Check: checkAccountSync},
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+)
+
+// Кто выполняет запрос. Если у браузера есть сессия входа через «Мой Универ»,
+// пользователь берется из нее, а параметры student и organization в запросе
+// должны с ней совпадать. Без сессии параметрам можно верить, только пока
+// вход через поставщика не настроен: так работает выгрузка из 1С, под которую
+// написан mock-server. С oidc_issuer запрос без сессии отклоняется.
+
+// requestStudent возвращает СНИЛС студента, от имени которого выполняется
+// запрос; claimed - СНИЛС из параметров или тела запроса. При отказе ответ
+// уже отправлен.
+func requestStudent(w http.ResponseWriter, r *http.Request, claimed string) (string, bool) {
+	s, ok := requestSession(r)
+	if !ok {
+		return claimed, requireSession(w)
+	}
+	if s.Account.Student == "" {
+		writeError(w, http.StatusForbidden, "доступно только студентам")
+		return "", false
+	}
+	if claimed != "" && claimed != s.Account.Student {
+		writeError(w, http.StatusForbidden, "нет доступа к данным другого пользователя")
+		return "", false
+	}
+	return s.Account.Student, true
+}
+
+// requestOrganization возвращает GUID организации, от имени которой
+// выполняется запрос, по тем же правилам, что и requestStudent
+func requestOrganization(w http.ResponseWriter, r *http.Request, claimed string) (string, bool) {
+	s, ok := requestSession(r)
+	if !ok {
+		return claimed, requireSession(w)
+	}
+	if s.Account.Organization == "" {
+		writeError(w, http.StatusForbidden, "доступно только работодателям")
+		return "", false
+	}
+	if claimed != "" && claimed != s.Account.Organization {
+		writeError(w, http.StatusForbidden, "нет доступа к данным другой организации")
+		return "", false
+	}
+	return s.Account.Organization, true
+}
+
+// sessionOrganization возвращает GUID организации из сессии. В отличие от
+// requestOrganization, параметрам не верит и без oidc_issuer: так выдаются
+// права, которые нельзя отдать по одному GUID.
+func sessionOrganization(w http.ResponseWriter, r *http.Request, claimed string) (string, bool) {
+	if _, ok := requestSession(r); !ok {
+		writeError(w, http.StatusUnauthorized, "требуется вход через «Мой Универ»")
+		return "", false
+	}
+	return requestOrganization(w, r, claimed)
+}
+
+// requestLogin возвращает логин пользователя, выполняющего запрос, по тем же
+// правилам; claimed - логин из параметров или тела запроса
+func requestLogin(w http.ResponseWriter, r *http.Request, claimed string) (string, bool) {
+	s, ok := requestSession(r)
+	if !ok {
+		return claimed, requireSession(w)
+	}
+	if claimed != "" && claimed != s.Login {
+		writeError(w, http.StatusForbidden, "нет доступа к данным другого пользователя")
+		return "", false
+	}
+	return s.Login, true
+}
+
+// requestStaff возвращает логин сотрудника с одной из ролей roles. Роли
+// выдаются только по сессии: параметрам staff, moderator и admin сервер не верит
+// даже без oidc_issuer, иначе права получил бы любой, кто знает логин.
+func requestStaff(w http.ResponseWriter, r *http.Request, denied string, roles ...string) (string, bool) {
+	s, ok := requestSession(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "требуется вход через «Мой Универ»")
+		return "", false
+	}
+	if !slices.Contains(roles, s.Account.Role) {
+		writeError(w, http.StatusForbidden, denied)
+		return "", false
+	}
+	return s.Login, true
+}
+
+// requestAdmin возвращает логин администратора, выполняющего запрос
+func requestAdmin(w http.ResponseWriter, r *http.Request) (string, bool) {
+	return requestStaff(w, r, "доступно только администраторам", RoleAdmin)
+}
+
+// requestModerator возвращает логин модератора или администратора,
+// выполняющего запрос
+func requestModerator(w http.ResponseWriter, r *http.Request) (string, bool) {
+	return requestStaff(w, r, "доступно только модераторам", RoleModerator, RoleAdmin)
+}
+
+// requestSupport возвращает логин сотрудника, работающего с обращениями
+func requestSupport(w http.ResponseWriter, r *http.Request) (string, bool) {
+	return requestStaff(w, r, "доступно только сотрудникам", RoleSupport, RoleModerator, RoleAdmin)
+}
+
+// requireSession отклоняет запрос без сессии, если настроен вход через
+// поставщика удостоверений
+func requireSession(w http.ResponseWriter) bool {
+	if sso == nil {
+		return true
+	}
+	writeError(w, http.StatusUnauthorized, "требуется вход через «Мой Универ»")
+	return false
+}
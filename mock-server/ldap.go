@@ -0,0 +1,447 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Клиент LDAP v3 с минимальным набором операций: простая привязка, поиск
+// с постраничной выдачей и отвязка. Сообщения кодируются в BER вручную:
+// encoding/asn1 не принимает неминимальную запись длины, которую
+// использует Active Directory.
+
+// Теги BER
+const (
+	berBoolean     = 0x01
+	berInteger     = 0x02
+	berOctetString = 0x04
+	berEnumerated  = 0x0a
+	berSequence    = 0x30
+	berSet         = 0x31
+)
+
+// Операции LDAP (теги APPLICATION)
+const (
+	ldapBindRequest      = 0x60
+	ldapBindResponse     = 0x61
+	ldapUnbindRequest    = 0x42
+	ldapSearchRequest    = 0x63
+	ldapSearchEntry      = 0x64
+	ldapSearchDone       = 0x65
+	ldapSearchReference  = 0x73
+	ldapControls         = 0xa0
+	ldapFilterAnd        = 0xa0
+	ldapFilterOr         = 0xa1
+	ldapFilterNot        = 0xa2
+	ldapFilterEquality   = 0xa3
+	ldapFilterPresent    = 0x87
+	ldapSimpleAuth       = 0x80
+	ldapResultSuccess    = 0
+	ldapResultBadCreds   = 49
+	ldapPagedResultsOID  = "1.2.840.113556.1.4.319"
+	ldapPageSize         = 500
+	ldapMaxMessageLength = 16 << 20
+)
+
+// berTLV кодирует элемент: тег, длина в определенной форме, содержимое
+func berTLV(tag byte, content []byte) []byte {
+	out := []byte{tag}
+	switch n := len(content); {
+	case n < 0x80:
+		out = append(out, byte(n))
+	case n <= 0xff:
+		out = append(out, 0x81, byte(n))
+	case n <= 0xffff:
+		out = append(out, 0x82, byte(n>>8), byte(n))
+	default:
+		out = append(out, 0x84, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	return append(out, content...)
+}
+
+func berInt(tag byte, v int) []byte {
+	b := []byte{byte(v)}
+	for v >>= 8; v != 0 && v != -1; v >>= 8 {
+		b = append([]byte{byte(v)}, b...)
+	}
+	// Старший бит задает знак: положительному числу нужен ведущий ноль
+	if v == 0 && b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	return berTLV(tag, b)
+}
+
+func berString(tag byte, s string) []byte {
+	return berTLV(tag, []byte(s))
+}
+
+func berBool(v bool) []byte {
+	if v {
+		return berTLV(berBoolean, []byte{0xff})
+	}
+	return berTLV(berBoolean, []byte{0})
+}
+
+func berSeq(tag byte, parts ...[]byte) []byte {
+	var content []byte
+	for _, p := range parts {
+		content = append(content, p...)
+	}
+	return berTLV(tag, content)
+}
+
+// berElement - разобранный элемент BER
+type berElement struct {
+	tag  byte
+	data []byte
+}
+
+// parseBER разбирает первый элемент из data
+func parseBER(data []byte) (berElement, []byte, error) {
+	if len(data) < 2 {
+		return berElement{}, nil, io.ErrUnexpectedEOF
+	}
+	tag, l := data[0], int(data[1])
+	data = data[2:]
+	if tag&0x1f == 0x1f {
+		return berElement{}, nil, errors.New("BER: многобайтовые теги не поддерживаются")
+	}
+	if l&0x80 != 0 {
+		n := l & 0x7f
+		if n == 0 || n > 4 || len(data) < n {
+			return berElement{}, nil, errors.New("BER: некорректная длина")
+		}
+		l = 0
+		for _, b := range data[:n] {
+			l = l<<8 | int(b)
+		}
+		data = data[n:]
+	}
+	if l < 0 || l > len(data) {
+		return berElement{}, nil, io.ErrUnexpectedEOF
+	}
+	return berElement{tag: tag, data: data[:l]}, data[l:], nil
+}
+
+// children разбирает содержимое составного элемента
+func (e berElement) children() ([]berElement, error) {
+	var out []berElement
+	for data := e.data; len(data) > 0; {
+		child, rest, err := parseBER(data)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, child)
+		data = rest
+	}
+	return out, nil
+}
+
+func (e berElement) int() int {
+	v := 0
+	for i, b := range e.data {
+		if i == 0 && b&0x80 != 0 {
+			v = -1
+		}
+		v = v<<8 | int(b)
+	}
+	return v
+}
+
+func (e berElement) str() string {
+	return string(e.data)
+}
+
+// readBER читает из потока одно сообщение целиком
+func readBER(r *bufio.Reader) ([]byte, error) {
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return nil, err
+	}
+	msg := head
+	l := int(head[1])
+	if l&0x80 != 0 {
+		n := l & 0x7f
+		if n == 0 || n > 4 {
+			return nil, errors.New("BER: некорректная длина")
+		}
+		ext := make([]byte, n)
+		if _, err := io.ReadFull(r, ext); err != nil {
+			return nil, err
+		}
+		msg = append(msg, ext...)
+		l = 0
+		for _, b := range ext {
+			l = l<<8 | int(b)
+		}
+	}
+	if l > ldapMaxMessageLength {
+		return nil, fmt.Errorf("LDAP: сообщение длиной %d байт", l)
+	}
+	body := make([]byte, l)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return append(msg, body...), nil
+}
+
+// ldapResult разбирает LDAPResult: код и сообщение сервера
+func ldapResult(op berElement) (int, string, error) {
+	parts, err := op.children()
+	if err != nil || len(parts) < 3 {
+		return 0, "", errors.New("LDAP: некорректный ответ")
+	}
+	return parts[0].int(), parts[2].str(), nil
+}
+
+// ldapEqual и ldapPresent строят фильтры поиска. Фильтры собираются
+// из значений, а не из строки, поэтому логин не нужно экранировать.
+func ldapEqual(attr, value string) []byte {
+	return berSeq(ldapFilterEquality, berString(berOctetString, attr), berString(berOctetString, value))
+}
+
+func ldapPresent(attr string) []byte {
+	return berString(ldapFilterPresent, attr)
+}
+
+// ldapEntry - найденная запись каталога. Имена атрибутов в нижнем регистре.
+type ldapEntry struct {
+	dn    string
+	attrs map[string][]string
+}
+
+func (e ldapEntry) first(attr string) string {
+	if v := e.attrs[strings.ToLower(attr)]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+type ldapConn struct {
+	conn   net.Conn
+	r      *bufio.Reader
+	nextID int
+}
+
+func (c *ldapConn) send(op []byte, controls ...[]byte) (int, error) {
+	c.nextID++
+	parts := [][]byte{berInt(berInteger, c.nextID), op}
+	if len(controls) > 0 {
+		parts = append(parts, berSeq(ldapControls, controls...))
+	}
+	_, err := c.conn.Write(berSeq(berSequence, parts...))
+	return c.nextID, err
+}
+
+// receive читает ответ на сообщение id: операцию и элементы управления
+func (c *ldapConn) receive(id int) (berElement, []berElement, error) {
+	for {
+		data, err := readBER(c.r)
+		if err != nil {
+			return berElement{}, nil, err
+		}
+		msg, _, err := parseBER(data)
+		if err != nil {
+			return berElement{}, nil, err
+		}
+		parts, err := msg.children()
+		if err != nil || len(parts) < 2 {
+			return berElement{}, nil, errors.New("LDAP: некорректное сообщение")
+		}
+		// Уведомления сервера (messageID 0) пропускаются
+		if parts[0].int() != id {
+			continue
+		}
+		var controls []berElement
+		if len(parts) > 2 && parts[2].tag == ldapControls {
+			controls, _ = parts[2].children()
+		}
+		return parts[1], controls, nil
+	}
+}
+
+func (c *ldapConn) bind(dn, password string) error {
+	id, err := c.send(berSeq(ldapBindRequest,
+		berInt(berInteger, 3), berString(berOctetString, dn), berString(ldapSimpleAuth, password)))
+	if err != nil {
+		return err
+	}
+	op, _, err := c.receive(id)
+	if err != nil {
+		return err
+	}
+	if op.tag != ldapBindResponse {
+		return errors.New("LDAP: неожиданный ответ на привязку")
+	}
+	code, message, err := ldapResult(op)
+	if err != nil {
+		return err
+	}
+	if code != ldapResultSuccess {
+		return fmt.Errorf("LDAP: привязка отклонена, код %d: %s", code, message)
+	}
+	return nil
+}
+
+// search ищет записи в поддереве base. Если сервер поддерживает постраничную
+// выдачу (Active Directory отдает без нее не больше 1000 записей), страницы
+// запрашиваются до конца.
+func (c *ldapConn) search(base string, filter []byte, attrs []string, limit int) ([]ldapEntry, error) {
+	attrList := make([][]byte, len(attrs))
+	for i, a := range attrs {
+		attrList[i] = berString(berOctetString, a)
+	}
+	var entries []ldapEntry
+	var cookie []byte
+	for {
+		op := berSeq(ldapSearchRequest,
+			berString(berOctetString, base),
+			berInt(berEnumerated, 2), // поддерево
+			berInt(berEnumerated, 0), // без разыменования псевдонимов
+			berInt(berInteger, limit),
+			berInt(berInteger, 0),
+			berBool(false),
+			filter,
+			berSeq(berSequence, attrList...))
+		paging := berSeq(berSequence,
+			berString(berOctetString, ldapPagedResultsOID),
+			berTLV(berOctetString, berSeq(berSequence, berInt(berInteger, ldapPageSize), berTLV(berOctetString, cookie))))
+		id, err := c.send(op, paging)
+		if err != nil {
+			return nil, err
+		}
+
+		cookie = nil
+		for done := false; !done; {
+			resp, controls, err := c.receive(id)
+			if err != nil {
+				return nil, err
+			}
+			switch resp.tag {
+			case ldapSearchEntry:
+				e, err := parseLDAPEntry(resp)
+				if err != nil {
+					return nil, err
+				}
+				entries = append(entries, e)
+			case ldapSearchReference:
+				// Ссылки на другие серверы не обходятся
+			case ldapSearchDone:
+				code, message, err := ldapResult(resp)
+				if err != nil {
+					return nil, err
+				}
+				if code != ldapResultSuccess {
+					return nil, fmt.Errorf("LDAP: поиск завершился с кодом %d: %s", code, message)
+				}
+				cookie = pagingCookie(controls)
+				done = true
+			default:
+				return nil, fmt.Errorf("LDAP: неожиданный ответ 0x%x", resp.tag)
+			}
+		}
+		if len(cookie) == 0 || (limit > 0 && len(entries) >= limit) {
+			return entries, nil
+		}
+	}
+}
+
+func parseLDAPEntry(op berElement) (ldapEntry, error) {
+	parts, err := op.children()
+	if err != nil || len(parts) < 2 {
+		return ldapEntry{}, errors.New("LDAP: некорректная запись")
+	}
+	e := ldapEntry{dn: parts[0].str(), attrs: map[string][]string{}}
+	attrs, err := parts[1].children()
+	if err != nil {
+		return ldapEntry{}, err
+	}
+	for _, a := range attrs {
+		av, err := a.children()
+		if err != nil || len(av) < 2 {
+			return ldapEntry{}, errors.New("LDAP: некорректный атрибут")
+		}
+		vals, err := av[1].children()
+		if err != nil {
+			return ldapEntry{}, err
+		}
+		name := strings.ToLower(av[0].str())
+		for _, v := range vals {
+			e.attrs[name] = append(e.attrs[name], v.str())
+		}
+	}
+	return e, nil
+}
+
+// pagingCookie возвращает признак следующей страницы из элементов управления
+func pagingCookie(controls []berElement) []byte {
+	for _, c := range controls {
+		parts, err := c.children()
+		if err != nil || len(parts) < 2 || parts[0].str() != ldapPagedResultsOID {
+			continue
+		}
+		value := parts[len(parts)-1]
+		v, _, err := parseBER(value.data)
+		if err != nil {
+			return nil
+		}
+		fields, err := v.children()
+		if err != nil || len(fields) < 2 {
+			return nil
+		}
+		return fields[1].data
+	}
+	return nil
+}
+
+func (c *ldapConn) close() {
+	c.send(berTLV(ldapUnbindRequest, nil))
+	c.conn.Close()
+}
+
+// dialLDAP подключается к серверу ldap:// или ldaps://
+func dialLDAP(ctx context.Context, rawURL string, tlsConfig *tls.Config) (*ldapConn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	host := u.Host
+	var conn net.Conn
+	switch u.Scheme {
+	case "ldap":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "389")
+		}
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", host)
+	case "ldaps":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "636")
+		}
+		cfg := tlsConfig.Clone()
+		if cfg == nil {
+			cfg = &tls.Config{}
+		}
+		cfg.ServerName = u.Hostname()
+		cfg.MinVersion = tls.VersionTLS12
+		conn, err = (&tls.Dialer{Config: cfg}).DialContext(ctx, "tcp", host)
+	default:
+		return nil, fmt.Errorf("LDAP: неподдерживаемая схема %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(ldapTimeout)
+	}
+	conn.SetDeadline(deadline)
+	return &ldapConn{conn: conn, r: bufio.NewReader(conn)}, nil
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"maps"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testDirectoryAccounts = map[string]Account{
+	"ivanov.ii":   {Student: "123-694-775 67"},
+	"ivanov.iv":   {Organization: "f2742040-cdb4-11f0-ae42-38d57ae2c1c1"},
+	"petrov.pp":   {Organization: "4c09ed30-cdb6-11f0-ae42-38d57ae2c1c1"},
+	"smirnova.dp": {Student: "234-567-890 12"},
+	"sidorova.an": {Role: RoleSupport},
+	"orlov.dm":    {Role: RoleModerator},
+	"head.it":     {Role: RoleAdmin},
+}
+
+// startTestLDAP запускает встроенный каталог на свободном порту и
+// возвращает настройки подключения к нему
+func startTestLDAP(t *testing.T, d *devLDAP) LDAPConfig {
+	t.Helper()
+	ln, err := serveDevLDAP("127.0.0.1:0", d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	return LDAPConfig{
+		URL:          "ldap://" + ln.Addr().String(),
+		BindDN:       d.bindDN,
+		BindPassword: d.bindPassword,
+		BaseDN:       devLDAPBaseDN,
+		LoginAttr:    "uid",
+	}
+}
+
+func TestLDAPLookup(t *testing.T) {
+	cfg := startTestLDAP(t, &devLDAP{entries: devLDAPDirectory(testDirectoryAccounts)})
+	l := NewLDAPAccounts(cfg, nil)
+	ctx := context.Background()
+
+	a, ok, err := l.Lookup(ctx, "ivanov.ii")
+	if err != nil || !ok {
+		t.Fatalf("Lookup(ivanov.ii) = %v, %v", ok, err)
+	}
+	if a.Student != "123-694-775 67" || a.Organization != "" || a.Role != "" {
+		t.Errorf("Lookup(ivanov.ii) = %+v", a)
+	}
+
+	a, ok, err = l.Lookup(ctx, "ivanov.iv")
+	if err != nil || !ok || a.Organization != "f2742040-cdb4-11f0-ae42-38d57ae2c1c1" {
+		t.Errorf("Lookup(ivanov.iv) = %+v, %v, %v", a, ok, err)
+	}
+
+	for _, login := range []string{"nobody", "", "ivanov.i*"} {
+		if a, ok, err := l.Lookup(ctx, login); err != nil || ok {
+			t.Errorf("Lookup(%q) = %+v, %v, %v; want miss", login, a, ok, err)
+		}
+	}
+}
+
+func TestLDAPLookupLoginAttr(t *testing.T) {
+	cfg := startTestLDAP(t, &devLDAP{entries: devLDAPDirectory(testDirectoryAccounts)})
+	cfg.LoginAttr = "sAMAccountName"
+	a, ok, err := NewLDAPAccounts(cfg, nil).Lookup(context.Background(), "smirnova.dp")
+	if err != nil || !ok || a.Student != "234-567-890 12" {
+		t.Errorf("Lookup(smirnova.dp) = %+v, %v, %v", a, ok, err)
+	}
+}
+
+func TestLDAPLookupDuplicate(t *testing.T) {
+	entries := devLDAPDirectory(testDirectoryAccounts)
+	// Тот же логин в другом подразделении
+	entries = append(entries, ldapEntry{
+		dn:    "uid=ivanov.ii,ou=staff," + devLDAPBaseDN,
+		attrs: map[string][]string{"uid": {"ivanov.ii"}, "departmentnumber": {"f2742040-cdb4-11f0-ae42-38d57ae2c1c1"}},
+	})
+	cfg := startTestLDAP(t, &devLDAP{entries: entries})
+
+	if a, ok, err := NewLDAPAccounts(cfg, nil).Lookup(context.Background(), "ivanov.ii"); err == nil {
+		t.Errorf("Lookup(ivanov.ii) = %+v, %v; want error", a, ok)
+	}
+}
+
+func TestLDAPGroupRoles(t *testing.T) {
+	entries := devLDAPDirectory(testDirectoryAccounts)
+	entries = append(entries,
+		ldapEntry{dn: "uid=both,ou=people," + devLDAPBaseDN, attrs: map[string][]string{
+			"uid": {"both"},
+			"memberof": {
+				"CN=JobService-Moderators,OU=Groups,DC=dvfu,DC=ru",
+				"CN=jobservice-admins,OU=Groups,DC=dvfu,DC=ru",
+			},
+		}},
+		ldapEntry{dn: "uid=other,ou=people," + devLDAPBaseDN, attrs: map[string][]string{
+			"uid":      {"other"},
+			"memberof": {"cn=library,ou=groups,dc=dvfu,dc=ru", "ou=jobservice-admins,dc=dvfu,dc=ru"},
+		}},
+	)
+	cfg := startTestLDAP(t, &devLDAP{entries: entries})
+	l := NewLDAPAccounts(cfg, nil)
+
+	tests := []struct {
+		login, role string
+	}{
+		{"head.it", RoleAdmin},
+		{"orlov.dm", RoleModerator},
+		{"sidorova.an", RoleSupport},
+		{"ivanov.ii", ""},
+		// Старшая роль из нескольких, CN без учета регистра
+		{"both", RoleAdmin},
+		// Группа засчитывается только по CN
+		{"other", ""},
+	}
+	for _, tt := range tests {
+		a, ok, err := l.Lookup(context.Background(), tt.login)
+		if err != nil || !ok {
+			t.Errorf("Lookup(%q) = %v, %v", tt.login, ok, err)
+			continue
+		}
+		if a.Role != tt.role {
+			t.Errorf("Lookup(%q).Role = %q, want %q (groups %v)", tt.login, a.Role, tt.role, a.Groups)
+		}
+	}
+}
+
+func TestLDAPBind(t *testing.T) {
+	d := &devLDAP{
+		entries:      devLDAPDirectory(testDirectoryAccounts),
+		bindDN:       "cn=jobservice,ou=services," + devLDAPBaseDN,
+		bindPassword: "s3cret",
+	}
+	cfg := startTestLDAP(t, d)
+	ctx := context.Background()
+
+	if _, ok, err := NewLDAPAccounts(cfg, nil).Lookup(ctx, "ivanov.ii"); err != nil || !ok {
+		t.Fatalf("Lookup with valid bind = %v, %v", ok, err)
+	}
+
+	wrong := cfg
+	wrong.BindPassword = "wrong"
+	_, _, err := NewLDAPAccounts(wrong, nil).Lookup(ctx, "ivanov.ii")
+	if err == nil || !strings.Contains(err.Error(), "49") {
+		t.Errorf("Lookup with wrong password: err = %v, want invalidCredentials", err)
+	}
+
+	anonymous := cfg
+	anonymous.BindDN, anonymous.BindPassword = "", ""
+	if _, err := NewLDAPAccounts(anonymous, nil).List(ctx); err == nil {
+		t.Error("List without bind succeeded, want error")
+	}
+}
+
+func TestLDAPListPaged(t *testing.T) {
+	accounts := maps.Clone(testDirectoryAccounts)
+	// Запись вне BaseDN не попадает в выдачу
+	entries := append(devLDAPDirectory(accounts), ldapEntry{
+		dn:    "uid=outside,ou=people,dc=example,dc=com",
+		attrs: map[string][]string{"uid": {"outside"}},
+	})
+	for _, pageSize := range []int{0, 1, 3, len(accounts)} {
+		cfg := startTestLDAP(t, &devLDAP{entries: entries, pageSize: pageSize})
+		got, err := NewLDAPAccounts(cfg, nil).List(context.Background())
+		if err != nil {
+			t.Fatalf("pageSize %d: List: %v", pageSize, err)
+		}
+		if len(got) != len(accounts) {
+			t.Errorf("pageSize %d: List returned %d accounts, want %d", pageSize, len(got), len(accounts))
+		}
+		for login, want := range accounts {
+			a := got[login]
+			if a.Student != want.Student || a.Organization != want.Organization || a.Role != want.Role {
+				t.Errorf("pageSize %d: List()[%q] = %+v, want %+v", pageSize, login, a, want)
+			}
+		}
+	}
+}
+
+// countingProvider считает обращения к каталогу
+type countingProvider struct {
+	AccountProvider
+	lookups atomic.Int32
+}
+
+func (c *countingProvider) Lookup(ctx context.Context, login string) (Account, bool, error) {
+	c.lookups.Add(1)
+	return c.AccountProvider.Lookup(ctx, login)
+}
+
+// useAccountProvider подменяет источник учетных записей и кэш до конца теста
+func useAccountProvider(t *testing.T, p AccountProvider) {
+	t.Helper()
+	provider, db := accountProvider, accountsDB
+	accountMisses.Lock()
+	misses := accountMisses.m
+	accountMisses.m = nil
+	accountMisses.Unlock()
+	accountProvider, accountsDB = p, map[string]Account{}
+	resetSync := func() {
+		accountSync.Lock()
+		accountSync.interval, accountSync.last, accountSync.err = 0, time.Time{}, nil
+		accountSync.Unlock()
+	}
+	resetSync()
+	t.Cleanup(func() {
+		accountProvider, accountsDB = provider, db
+		accountMisses.Lock()
+		accountMisses.m = misses
+		accountMisses.Unlock()
+		resetSync()
+	})
+}
+
+func TestLookupAccountCache(t *testing.T) {
+	cfg := startTestLDAP(t, &devLDAP{entries: devLDAPDirectory(testDirectoryAccounts)})
+	p := &countingProvider{AccountProvider: NewLDAPAccounts(cfg, nil)}
+	useAccountProvider(t, p)
+	ctx := context.Background()
+
+	// Найденная запись кэшируется
+	if a, ok := lookupAccount(ctx, "ivanov.ii"); !ok || a.Student != "123-694-775 67" {
+		t.Fatalf("lookupAccount(ivanov.ii) = %+v, %v", a, ok)
+	}
+	lookupAccount(ctx, "ivanov.ii")
+	if n := p.lookups.Load(); n != 1 {
+		t.Errorf("directory lookups after two hits = %d, want 1", n)
+	}
+
+	// Отсутствие логина запоминается
+	for range 3 {
+		if _, ok := lookupAccount(ctx, "ghost"); ok {
+			t.Fatal("lookupAccount(ghost) found an account")
+		}
+	}
+	if n := p.lookups.Load(); n != 2 {
+		t.Errorf("directory lookups after repeated misses = %d, want 2", n)
+	}
+
+	// Синхронизация заменяет кэш и забывает промахи
+	if err := syncAccounts(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cachedAccount("orlov.dm"); !ok {
+		t.Error("orlov.dm is not cached after sync")
+	}
+	accountMisses.Lock()
+	misses := len(accountMisses.m)
+	accountMisses.Unlock()
+	if misses != 0 {
+		t.Errorf("%d misses remembered after sync, want 0", misses)
+	}
+	lookupAccount(ctx, "smirnova.dp")
+	if n := p.lookups.Load(); n != 2 {
+		t.Errorf("directory lookups after sync = %d, want 2", n)
+	}
+	lookupAccount(ctx, "ghost")
+	if n := p.lookups.Load(); n != 3 {
+		t.Errorf("directory lookups for a forgotten miss = %d, want 3", n)
+	}
+}
+
+func TestSyncAccountsFailure(t *testing.T) {
+	cfg := startTestLDAP(t, &devLDAP{entries: devLDAPDirectory(testDirectoryAccounts)})
+	useAccountProvider(t, NewLDAPAccounts(cfg, nil))
+	ctx := context.Background()
+
+	accountSync.Lock()
+	accountSync.interval = time.Minute
+	accountSync.Unlock()
+	if err := checkAccountSync(); err == nil {
+		t.Error("checkAccountSync before the first sync = nil, want error")
+	}
+	if err := syncAccounts(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkAccountSync(); err != nil {
+		t.Errorf("checkAccountSync after sync = %v", err)
+	}
+
+	// Каталог недоступен: кэш остается прежним, готовность снимается
+	cfg.URL = "ldap://127.0.0.1:1"
+	accountProvider = NewLDAPAccounts(cfg, nil)
+	if err := syncAccounts(ctx); err == nil {
+		t.Fatal("syncAccounts with an unreachable directory succeeded")
+	}
+	if err := checkAccountSync(); err == nil {
+		t.Error("checkAccountSync after a failed sync = nil, want error")
+	}
+	if _, ok := cachedAccount("ivanov.ii"); !ok {
+		t.Error("failed sync dropped the cached accounts")
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"shared"
+)
+
+// Журнал запросов ведет shared.LoggingMiddleware, здесь - скрытие
+// персональных данных в параметрах и журнал обработчиков.
+
+// Параметры запроса с персональными данными и одноразовыми кодами входа,
+// которые не попадают в журнал
+var redactedParams = []string{"student", "user", "actor", "signature", "code", "state"}
+
+const redacted = "***"
+
+// snilsPattern находит СНИЛС вида 123-456-789 01 в произвольном тексте
+var snilsPattern = regexp.MustCompile(`\d{3}-\d{3}-\d{3}[ -]?\d{2}`)
+
+// requestLogger возвращает журнал с идентификатором текущего запроса
+func requestLogger(r *http.Request) *slog.Logger {
+	if id := shared.RequestID(r); id != "" {
+		return slog.With("request_id", id)
+	}
+	return slog.Default()
+}
+
+// redactQuery возвращает строку запроса без персональных данных
+func redactQuery(q url.Values) string {
+	var parts []string
+	for _, k := range slices.Sorted(maps.Keys(q)) {
+		for _, v := range q[k] {
+			if slices.Contains(redactedParams, k) {
+				v = redacted
+			} else {
+				v = redactText(v)
+			}
+			parts = append(parts, k+"="+v)
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// redactText заменяет СНИЛС в тексте
+func redactText(s string) string {
+	return snilsPattern.ReplaceAllString(s, redacted)
+}
@@ -1,18 +1,29 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"io"
-	"log"
+	"log/slog"
+	"maps"
 	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+
+	"shared"
 )
 
 // Models
 type Vacancy struct {
 	Organization   string    `json:"Organization"`
+	OrganizationID string    `json:"OrganizationID"`
 	Description    string    `json:"Description"`
 	DateOfBegin    time.Time `json:"DateOfBegin"`
 	DateOfEnd      time.Time `json:"DateOfEnd"`
@@ -24,6 +35,8 @@ type Vacancy struct {
 }
 
 type Request struct {
+	// ID - номер отклика. Number - номер вакансии, на которую откликнулся студент.
+	ID           string `json:"ID"`
 	Organization string `json:"Organization"`
 	Student      string `json:"Student"`
 	Description  string `json:"Description"`
@@ -32,23 +45,50 @@ type Request struct {
 	Number       string `json:"Number"`
 	Accept       bool   `json:"Accept"`
 	Good         bool   `json:"Good"`
+	Attachment   string `json:"Attachment,omitempty"`
 }
 
 type Notify struct {
 	Text            string    `json:"Text"`
 	Date            time.Time `json:"Date"`
 	NumberOfRequest string    `json:"NumberOfRequest"`
+	Student         string    `json:"Student,omitempty"`
+	Organization    string    `json:"Organization,omitempty"`
+	Ticket          string    `json:"Ticket,omitempty"`
 }
 
 type Account struct {
 	Organization string `json:"Organization"`
 	Student      string `json:"Student"`
+	Role         string `json:"Role,omitempty"`
+	// Groups - группы пользователя в каталоге университета
+	Groups []string `json:"Groups,omitempty"`
+}
+
+// Роли сотрудников университета
+const (
+	RoleAdmin     = "admin"
+	RoleModerator = "moderator"
+	RoleSupport   = "support"
+)
+
+// staffAccount возвращает аккаунт сотрудника по логину
+func staffAccount(ctx context.Context, login string) (Account, bool) {
+	account, ok := lookupAccount(ctx, login)
+	if !ok || account.Role == "" {
+		return Account{}, false
+	}
+	return account, true
 }
 
 // Mock data
+// dataMu защищает все данные ниже, а также данные остальных файлов сервера
+var dataMu sync.RWMutex
+
 var vacancies = []Vacancy{
 	{
 		Organization:   "Волонтеры ДВФУ",
+		OrganizationID: "b3c1d2e4-0001-11f0-ae42-38d57ae2c1c1",
 		Description:    "Необходимо доставлять гуманитарную помощь, покупать лекарства для пожилых немобильных людей",
 		DateOfBegin:    parseDate("20260101"),
 		DateOfEnd:      parseDate("20270101"),
@@ -60,6 +100,7 @@ var vacancies = []Vacancy{
 	},
 	{
 		Organization:   "Волонтеры ДВФУ",
+		OrganizationID: "b3c1d2e4-0001-11f0-ae42-38d57ae2c1c1",
 		Description:    "Сбор мусора на набережной, очистка прибрежной полосы от пластика и мусора",
 		DateOfBegin:    parseDate("20260101"),
 		DateOfEnd:      parseDate("20270101"),
@@ -71,6 +112,7 @@ var vacancies = []Vacancy{
 	},
 	{
 		Organization:   "CODE WORK",
+		OrganizationID: "f2742040-cdb4-11f0-ae42-38d57ae2c1c1",
 		Description:    "Необходимо обучать программированию студентов 1-2 курсов на языке С++, подготовка к олимпиадам",
 		DateOfBegin:    parseDate("20260601"),
 		DateOfEnd:      parseDate("20260701"),
@@ -82,6 +124,7 @@ var vacancies = []Vacancy{
 	},
 	{
 		Organization:   "Tech Startup",
+		OrganizationID: "4c09ed30-cdb6-11f0-ae42-38d57ae2c1c1",
 		Description:    "Разработка backend API на Go, опыт обязателен, работа с PostgreSQL и Docker",
 		DateOfBegin:    parseDate("20260215"),
 		DateOfEnd:      parseDate("20260630"),
@@ -93,6 +136,7 @@ var vacancies = []Vacancy{
 	},
 	{
 		Organization:   "DVFU Research Lab",
+		OrganizationID: "b3c1d2e4-0002-11f0-ae42-38d57ae2c1c1",
 		Description:    "Помощь в проведении научных исследований в области искусственного интеллекта, обработка данных",
 		DateOfBegin:    parseDate("20260201"),
 		DateOfEnd:      parseDate("20261031"),
@@ -104,6 +148,7 @@ var vacancies = []Vacancy{
 	},
 	{
 		Organization:   "Hospital №1",
+		OrganizationID: "7a8b9c0d-1e2f-3a4b-5c6d-7e8f9a0b1c2d",
 		Description:    "Администратор медицинского центра, работа с пациентами и документацией",
 		DateOfBegin:    parseDate("20260101"),
 		DateOfEnd:      parseDate("20261231"),
@@ -115,6 +160,7 @@ var vacancies = []Vacancy{
 	},
 	{
 		Organization:   "Vladivostok Creative Studio",
+		OrganizationID: "b3c1d2e4-0003-11f0-ae42-38d57ae2c1c1",
 		Description:    "Работа в команде креативных дизайнеров, создание графического контента для проектов",
 		DateOfBegin:    parseDate("20260315"),
 		DateOfEnd:      parseDate("20260915"),
@@ -126,6 +172,7 @@ var vacancies = []Vacancy{
 	},
 	{
 		Organization:   "Literature Center DVFU",
+		OrganizationID: "b3c1d2e4-0004-11f0-ae42-38d57ae2c1c1",
 		Description:    "Редакция университетского издания, работа с текстами и публикациями",
 		DateOfBegin:    parseDate("20260201"),
 		DateOfEnd:      parseDate("20260930"),
@@ -137,6 +184,7 @@ var vacancies = []Vacancy{
 	},
 	{
 		Organization:   "Tech Startup",
+		OrganizationID: "4c09ed30-cdb6-11f0-ae42-38d57ae2c1c1",
 		Description:    "Фронтенд разработчик для веб-приложения, опыт с React и TypeScript приветствуется",
 		DateOfBegin:    parseDate("20260301"),
 		DateOfEnd:      parseDate("20260831"),
@@ -148,6 +196,7 @@ var vacancies = []Vacancy{
 	},
 	{
 		Organization:   "ICPC Training Center",
+		OrganizationID: "b3c1d2e4-0005-11f0-ae42-38d57ae2c1c1",
 		Description:    "Подготовка студентов к чемпионатам по программированию, тренировки по алгоритмам",
 		DateOfBegin:    parseDate("20260101"),
 		DateOfEnd:      parseDate("20261231"),
@@ -159,6 +208,7 @@ var vacancies = []Vacancy{
 	},
 	{
 		Organization:   "Data Science Lab",
+		OrganizationID: "e1f2a3b4-c5d6-7e8f-9a0b-1c2d3e4f5a6b",
 		Description:    "Работа с большими данными, машинное обучение, анализ статистики",
 		DateOfBegin:    parseDate("20260210"),
 		DateOfEnd:      parseDate("20261210"),
@@ -170,6 +220,7 @@ var vacancies = []Vacancy{
 	},
 	{
 		Organization:   "Green Initiative DVFU",
+		OrganizationID: "b3c1d2e4-0006-11f0-ae42-38d57ae2c1c1",
 		Description:    "Экологический проект, уборка парков и посадка деревьев",
 		DateOfBegin:    parseDate("20260320"),
 		DateOfEnd:      parseDate("20261020"),
@@ -181,6 +232,7 @@ var vacancies = []Vacancy{
 	},
 	{
 		Organization:   "Mobile Dev Studio",
+		OrganizationID: "b3c1d2e4-0007-11f0-ae42-38d57ae2c1c1",
 		Description:    "Разработка мобильных приложений на Flutter и Kotlin, опыт в мобильной разработке",
 		DateOfBegin:    parseDate("20260225"),
 		DateOfEnd:      parseDate("20260825"),
@@ -192,6 +244,7 @@ var vacancies = []Vacancy{
 	},
 	{
 		Organization:   "Medical Research Institute",
+		OrganizationID: "b3c1d2e4-0008-11f0-ae42-38d57ae2c1c1",
 		Description:    "Помощь в медицинских исследованиях, работа с пациентами и документацией",
 		DateOfBegin:    parseDate("20260320"),
 		DateOfEnd:      parseDate("20261120"),
@@ -203,6 +256,7 @@ var vacancies = []Vacancy{
 	},
 	{
 		Organization:   "Vladivostok Library",
+		OrganizationID: "b3c1d2e4-0009-11f0-ae42-38d57ae2c1c1",
 		Description:    "Каталогизация книг, работа с библиотечной системой, помощь посетителям",
 		DateOfBegin:    parseDate("20260201"),
 		DateOfEnd:      parseDate("20261231"),
@@ -214,6 +268,7 @@ var vacancies = []Vacancy{
 	},
 	{
 		Organization:   "IoT Innovations",
+		OrganizationID: "b3c1d2e4-0010-11f0-ae42-38d57ae2c1c1",
 		Description:    "Разработка на микроконтроллерах Arduino и Raspberry Pi, встроенные системы",
 		DateOfBegin:    parseDate("20260401"),
 		DateOfEnd:      parseDate("20261001"),
@@ -225,10 +280,14 @@ var vacancies = []Vacancy{
 	},
 }
 
+// lastVacancyNumber - последний выданный номер вакансии
+var lastVacancyNumber = 18
+
 var requests = []Request{
 	{
+		ID:           "000000001",
 		Organization: "Волонтеры ДВФУ",
-		Student:      "Тестов Тест Тестович",
+		Student:      "456-789-012 34",
 		Description:  "Очень хочу попробовать поработать волонтером, но нет опыта, имею свой транспорт.",
 		StartPeriod:  "26.01.2026 0:00:00",
 		EndPeriod:    "06.02.2026 0:00:00",
@@ -237,37 +296,43 @@ var requests = []Request{
 		Good:         false,
 	},
 	{
+		ID:           "000000002",
 		Organization: "CODE WORK",
-		Student:      "Иванов Иван Иванович",
+		Student:      "123-694-775 67",
 		Description:  "Опыт преподавания 3 года, люблю работать со студентами",
 		StartPeriod:  "01.06.2026 0:00:00",
 		EndPeriod:    "01.07.2026 0:00:00",
-		Number:       "000000004",
+		Number:       "000000001",
 		Accept:       false,
 		Good:         true,
 	},
 	{
+		ID:           "000000003",
 		Organization: "Tech Startup",
-		Student:      "Петров Петр Петрович",
+		Student:      "345-678-901 23",
 		Description:  "Разработчик с опытом 5 лет, знаю Go, PostgreSQL, Docker",
 		StartPeriod:  "15.02.2026 0:00:00",
 		EndPeriod:    "30.06.2026 0:00:00",
-		Number:       "000000004",
+		Number:       "000000006",
 		Accept:       false,
 		Good:         false,
 	},
 	{
+		ID:           "000000004",
 		Organization: "Tech Startup",
-		Student:      "Петров Петр Петрович",
+		Student:      "345-678-901 23",
 		Description:  "Разработчик с опытом 10 лет, знаю Go, PostgreSQL, Docker",
 		StartPeriod:  "15.02.2026 0:00:00",
 		EndPeriod:    "30.06.2026 0:00:00",
-		Number:       "000000004",
+		Number:       "000000011",
 		Accept:       true,
 		Good:         true,
 	},
 }
 
+// lastRequestID - последний выданный номер отклика
+var lastRequestID = 4
+
 var notifies = []Notify{
 	{
 		Text:            "Уважаемый Николаев Николай Николаевич! \n Одобрена ваша заявка по вакансии на должность Учитель по программированию на С++. \n Сообщение от руководителя: Подходите в кабинет C315 14.01.2026 с 13 до 14",
@@ -311,33 +376,13 @@ var notifies = []Notify{
 	},
 }
 
-var tags = []string{
-	"Наука",
-	"Медицина",
-	"Литература",
-	"Технологии",
-	"Творчество",
-	"Программирование",
-	"Алгоритмы",
-	"ICPC",
-	"Помощь пожилым",
-	"Общественная польза",
-	"Backend",
-	"Frontend",
-	"Go",
-	"API",
-	"Обучение",
-	"C++",
-	"Python",
-	"Web",
-	"Mobile",
-	"IoT",
-	"Дизайн",
-	"Редакция",
-	"Культура",
-	"Экология",
+// addNotify отправляет уведомление. Вызывающий держит dataMu.
+func addNotify(n Notify) {
+	notifies = append(notifies, n)
+	notificationsSent.Add(1)
 }
 
+// accountsDB - кэш учетных записей, его защищает accountsMu, а не dataMu
 var accountsDB = map[string]Account{
 	"ivanov.ii": {
 		Organization: "",
@@ -371,6 +416,21 @@ var accountsDB = map[string]Account{
 		Organization: "e1f2a3b4-c5d6-7e8f-9a0b-1c2d3e4f5a6b",
 		Student:      "",
 	},
+	"sidorova.an": {
+		Organization: "",
+		Student:      "",
+		Role:         RoleSupport,
+	},
+	"orlov.dm": {
+		Organization: "",
+		Student:      "",
+		Role:         RoleModerator,
+	},
+	"belova.ok": {
+		Organization: "",
+		Student:      "",
+		Role:         RoleAdmin,
+	},
 }
 
 func parseDate(dateStr string) time.Time {
@@ -381,171 +441,490 @@ func parseDate(dateStr string) time.Time {
 	return t
 }
 
-// CORS middleware
-func corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		w.Header().Set("Content-Type", "application/json")
-
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		next.ServeHTTP(w, r)
-	})
+// formatNumber возвращает номер документа в формате 1С: "000000042"
+func formatNumber(n int) string {
+	return fmt.Sprintf("%09d", n)
 }
 
 // Logger
+// Итог запроса пишет shared.LoggingMiddleware. Тело запроса в журнал не попадает:
+// в нем бывают персональные данные студентов.
 func logRequest(r *http.Request) {
-	body, _ := io.ReadAll(r.Body)
-	r.Body = io.NopCloser(strings.NewReader(string(body)))
+	requestLogger(r).Debug("запрос получен",
+		"method", r.Method,
+		"path", r.URL.Path,
+		"content_type", r.Header.Get("Content-Type"),
+		"content_length", r.ContentLength,
+	)
+}
 
-	fmt.Printf("\n%s %s\n", r.Method, r.URL.Path)
-	if r.URL.RawQuery != "" {
-		fmt.Printf("Query: %s\n", r.URL.RawQuery)
-	}
-	if len(body) > 0 {
-		fmt.Printf("Body: %s\n", string(body))
-	}
+// writeError отправляет ответ с ошибкой в том же формате, что и успешные ответы
+func writeError(w http.ResponseWriter, status int, message string) {
+	slog.Warn("ошибка запроса", "request_id", w.Header().Get(shared.RequestIDHeader), "status", status, "error", message)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"status": "error", "message": message})
 }
 
 // 1. Create Vacancy - POST /JobService/hs/jobservice/vacancy
+// Если модерация используется, вакансия публикуется только после одобрения модератором,
+// до этого она видна в /myvacancies.
 func createVacancy(w http.ResponseWriter, r *http.Request) {
 	logRequest(r)
 
-	var data map[string]interface{}
-	json.NewDecoder(r.Body).Decode(&data)
+	var data struct {
+		Salary       int    `json:"salary"`
+		Title        string `json:"title"`
+		DateOfBegin  string `json:"dateofbegin"`
+		DateOfEnd    string `json:"dateofend"`
+		Organization string `json:"organization"`
+		Description  string `json:"description"`
+		TypesOfWork  string `json:"typesofwork"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		writeError(w, http.StatusBadRequest, "некорректный JSON")
+		return
+	}
+	organization, ok := requestOrganization(w, r, data.Organization)
+	if !ok {
+		return
+	}
+	data.Organization = organization
+	if data.Title == "" || len(data.DateOfBegin) != 8 || len(data.DateOfEnd) != 8 {
+		writeError(w, http.StatusBadRequest, "не заполнены обязательные поля вакансии")
+		return
+	}
+
+	dataMu.Lock()
+	defer dataMu.Unlock()
+
+	org, ok := organizations[data.Organization]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "организация не найдена")
+		return
+	}
+	typesOfWork, err := canonicalTags(splitTypesOfWork(data.TypesOfWork))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	fmt.Println("✓ Вакансия создана")
+	lastVacancyNumber++
+	v := Vacancy{
+		Organization:   org.Name,
+		OrganizationID: org.ID,
+		Description:    data.Description,
+		DateOfBegin:    parseDate(data.DateOfBegin),
+		DateOfEnd:      parseDate(data.DateOfEnd),
+		Salary:         data.Salary,
+		Title:          data.Title,
+		DateOfDocument: time.Now(),
+		TypesOfWork:    typesOfWork,
+		Number:         formatNumber(lastVacancyNumber),
+	}
+	published := submitVacancy(v, org)
+	audit(r, accountLogin(org.ID, ""), AuditVacancyCreate, v.Number, nil, v)
+
+	requestLogger(r).Info("вакансия создана", "vacancy", v.Number, "moderation", !published)
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+	if published {
+		json.NewEncoder(w).Encode(map[string]string{"status": "success", "number": v.Number})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "success", "number": v.Number, "moderation": ModerationPending})
+}
+
+// splitTypesOfWork разбирает список направлений вида "Go,API,Backend"
+func splitTypesOfWork(s string) []string {
+	result := make([]string, 0)
+	for _, t := range strings.Split(s, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			result = append(result, t)
+		}
+	}
+	return result
 }
 
 // 2. Create Request - POST /JobService/hs/jobservice/request
+// Принимает JSON или multipart/form-data с теми же полями; в multipart-форме
+// в поле "resume" можно приложить резюме или портфолио (PDF, DOCX, PNG, JPEG).
+// Отклик с нарушениями в тексте передается работодателю только после модерации.
 func createRequest(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		StartPeriod string `json:"startperiod"`
+		EndPeriod   string `json:"endperiod"`
+		Student     string `json:"student"`
+		Description string `json:"description"`
+		Vacancy     string `json:"vacancy"`
+	}
+
+	multipartForm := strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data")
+	if multipartForm {
+		r.Body = http.MaxBytesReader(w, r.Body, maxAttachmentSize+1<<20)
+	}
 	logRequest(r)
 
-	var data map[string]interface{}
-	json.NewDecoder(r.Body).Decode(&data)
+	if multipartForm {
+		if err := r.ParseMultipartForm(maxAttachmentSize); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("размер файла не должен превышать %d МБ", maxAttachmentSize>>20))
+				return
+			}
+			writeError(w, http.StatusBadRequest, "некорректная форма")
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+		data.StartPeriod = r.FormValue("startperiod")
+		data.EndPeriod = r.FormValue("endperiod")
+		data.Student = r.FormValue("student")
+		data.Description = r.FormValue("description")
+		data.Vacancy = r.FormValue("vacancy")
+	} else if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		writeError(w, http.StatusBadRequest, "некорректный JSON")
+		return
+	}
+	student, ok := requestStudent(w, r, data.Student)
+	if !ok {
+		return
+	}
+	data.Student = student
+	if len(data.StartPeriod) != 8 || len(data.EndPeriod) != 8 || data.Student == "" {
+		writeError(w, http.StatusBadRequest, "не заполнены обязательные поля отклика")
+		return
+	}
+
+	dataMu.RLock()
+	vacancy, ok := findVacancy(data.Vacancy)
+	dataMu.RUnlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "вакансия не найдена")
+		return
+	}
 
-	fmt.Println("✓ Заявка на работу создана")
+	req := Request{
+		Organization: vacancy.Organization,
+		Student:      data.Student,
+		Description:  data.Description,
+		StartPeriod:  parseDate(data.StartPeriod).Format(requestPeriodLayout),
+		EndPeriod:    parseDate(data.EndPeriod).Format(requestPeriodLayout),
+		Number:       vacancy.Number,
+	}
+
+	var attachment *Attachment
+	if multipartForm {
+		file, header, err := r.FormFile(attachmentFormFile)
+		if err == nil {
+			defer file.Close()
+			a, err := saveAttachment(file, header, data.Student, vacancy)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			attachment = &a
+			req.Attachment = a.ID
+		} else if err != http.ErrMissingFile {
+			writeError(w, http.StatusBadRequest, "не удалось прочитать файл")
+			return
+		}
+	}
+
+	dataMu.Lock()
+	// Пока загружался файл, вакансию могли закрыть
+	if _, ok := findVacancy(req.Number); !ok {
+		dataMu.Unlock()
+		if attachment != nil {
+			deleteAttachmentFile(attachment.ID)
+		}
+		writeError(w, http.StatusNotFound, "вакансия не найдена")
+		return
+	}
+	if attachment != nil {
+		attachments[attachment.ID] = *attachment
+	}
+	lastRequestID++
+	req.ID = formatNumber(lastRequestID)
+	accepted := submitRequest(req)
+	dataMu.Unlock()
+	audit(r, accountLogin("", req.Student), AuditRequestCreate, req.ID, nil, req)
+
+	requestLogger(r).Info("отклик создан", "request", req.ID, "vacancy", req.Number, "moderation", !accepted)
 	w.WriteHeader(http.StatusOK)
+	if !accepted {
+		json.NewEncoder(w).Encode(map[string]string{"status": "success", "moderation": ModerationPending})
+		return
+	}
 	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
 }
 
+// Формат периода работы в отклике, как в выгрузке из 1С: "26.01.2026 0:00:00"
+const requestPeriodLayout = "02.01.2006 0:00:00"
+
+// findVacancy ищет вакансию по номеру
+func findVacancy(number string) (Vacancy, bool) {
+	for _, v := range vacancies {
+		if v.Number == number {
+			return v, true
+		}
+	}
+	return Vacancy{}, false
+}
+
+// requestVacancy ищет вакансию отклика, в том числе закрытую
+func requestVacancy(req Request) (Vacancy, bool) {
+	if v, ok := findVacancy(req.Number); ok {
+		return v, true
+	}
+	v, ok := closedVacancies[req.Number]
+	return v, ok
+}
+
+// findRequest возвращает индекс отклика по номеру или -1
+func findRequest(id string) int {
+	return slices.IndexFunc(requests, func(req Request) bool { return req.ID == id })
+}
+
 // 3. Get Vacancy List - GET /JobService/hs/jobservice/vacancylist
+// Параметр q включает полнотекстовый поиск: результаты упорядочиваются по релевантности.
 func getVacancyList(w http.ResponseWriter, r *http.Request) {
 	logRequest(r)
 
-	salaryMin := r.URL.Query().Get("salaryMIN")
-	typeOfWork := r.URL.Query().Get("typesofwork")
-	organization := r.URL.Query().Get("organization")
+	filter, err := parseVacancyFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	fmt.Printf("Фильтры: salaryMIN=%s, typesofwork=%s, organization=%s\n", salaryMin, typeOfWork, organization)
+	dataMu.RLock()
+	defer dataMu.RUnlock()
 
-	filtered := vacancies
-	fmt.Printf("✓ Возвращены вакансии: %d шт.\n", len(filtered))
+	filtered := filter.Apply(vacancies)
+	requestLogger(r).Debug("возвращены вакансии", "count", len(filtered))
 
+	w.Header().Set("X-Total-Count", strconv.Itoa(len(filtered)))
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(filtered)
 }
 
-// 4. Get Tags - GET /JobService/hs/jobservice/tags
-func getTags(w http.ResponseWriter, r *http.Request) {
-	logRequest(r)
-
-	fmt.Printf("✓ Возвращены теги: %d шт.\n", len(tags))
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(tags)
-}
-
-// 5. Get Request List - GET /JobService/hs/jobservice/requestlist/
+// 5. Get Request List - GET /JobService/hs/jobservice/requestlist/?vacancy=Number&organization=GUID
+// Отклики видит только организация, разместившая вакансию. Каждый отклик
+// дополняется краткой анкетой студента в поле Profile, если она заполнена.
 func getRequestList(w http.ResponseWriter, r *http.Request) {
 	logRequest(r)
 
-	vacancy := r.URL.Query().Get("vacancy")
-	fmt.Printf("Вакансия: %s\n", vacancy)
+	q := r.URL.Query()
+	organization, ok := requestOrganization(w, r, q.Get("organization"))
+	if !ok {
+		return
+	}
+	if organization == "" {
+		writeError(w, http.StatusUnauthorized, "не указана организация")
+		return
+	}
+	vacancy := q.Get("vacancy")
+
+	dataMu.RLock()
+	defer dataMu.RUnlock()
 
+	if v, ok := requestVacancy(Request{Number: vacancy}); !ok || v.OrganizationID != organization {
+		writeError(w, http.StatusForbidden, "нет доступа к откликам на эту вакансию")
+		return
+	}
 	var filtered []Request
-	if vacancy != "" {
-		for _, req := range requests {
-			if req.Number == vacancy {
-				filtered = append(filtered, req)
-			}
+	for _, req := range requests {
+		if req.Number == vacancy {
+			filtered = append(filtered, req)
 		}
-	} else {
-		filtered = requests
+	}
+
+	type requestWithProfile struct {
+		Request
+		Profile *ProfileSummary `json:"Profile,omitempty"`
 	}
 
 	result := make([]interface{}, 0, len(filtered)+1)
 	result = append(result, map[string]int{"count": len(filtered)})
 	for _, req := range filtered {
-		result = append(result, req)
+		result = append(result, requestWithProfile{Request: req, Profile: profileSummary(req.Student)})
 	}
 
+	w.Header().Set("X-Total-Count", strconv.Itoa(len(filtered)))
 	w.WriteHeader(http.StatusOK)
 	_ = json.NewEncoder(w).Encode(result)
 }
 
-
 // 6. Check Account - GET /JobService/hs/jobservice/checkaccount
+// Вход по одному логину, без пароля. Если настроен вход через «Мой Универ»,
+// он отключен: иначе любой мог бы войти под чужим логином.
 func checkAccount(w http.ResponseWriter, r *http.Request) {
 	logRequest(r)
 
+	if sso != nil {
+		writeError(w, http.StatusForbidden, "вход по логину отключен, войдите через «Мой Универ»")
+		return
+	}
 	user := r.URL.Query().Get("user")
-	fmt.Printf("Пользователь: %s\n", user)
+
+	now := time.Now()
+	key := loginKey(r, user)
+	if wait := loginLockedFor(key, now); wait > 0 {
+		loginLockouts.Add(1)
+		tooManyRequests(w, wait)
+		return
+	}
+
+	account, exists := lookupAccount(r.Context(), user)
 
 	var response Account
-	if account, exists := accountsDB[user]; exists {
+	if exists {
 		response = account
+		loginSuccesses.Add(1)
+		loginSucceeded(key)
+		audit(r, user, AuditLogin, user, nil, nil)
 	} else {
 		response = Account{Organization: "", Student: ""}
+		loginFailures.Add(1)
+		loginFailed(key, now)
 	}
 
-	fmt.Printf("✓ Аккаунт найден: %v\n", response)
+	requestLogger(r).Debug("проверен аккаунт", "role", accountRole(response))
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
 }
 
-// 7. Send FAQ Suggestion - POST /JobService/hs/jobservice/faq
-func sendFAQ(w http.ResponseWriter, r *http.Request) {
+// 8. Apply Request - POST /JobService/hs/jobservice/applyrequest
+// Отклик указывается номером в "id" и должен быть на вакансию организации.
+// Сообщение кандидату передается либо готовым текстом в "text", либо номером
+// шаблона организации в "template" и значениями подстановок в "variables".
+// ФИО студента, должность и даты вакансии подставляются автоматически,
+// если не переданы явно.
+func applyRequest(w http.ResponseWriter, r *http.Request) {
 	logRequest(r)
 
-	var data map[string]string
-	json.NewDecoder(r.Body).Decode(&data)
+	var data struct {
+		ID           string            `json:"id"`
+		Text         string            `json:"text"`
+		Organization string            `json:"organization"`
+		Template     string            `json:"template"`
+		Variables    map[string]string `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		writeError(w, http.StatusBadRequest, "некорректный JSON")
+		return
+	}
+	organization, ok := requestOrganization(w, r, data.Organization)
+	if !ok {
+		return
+	}
+	data.Organization = organization
 
-	fmt.Printf("Предложение: %s\n", data["suggestion"])
-	fmt.Println("✓ Предложение сохранено")
+	dataMu.Lock()
+	defer dataMu.Unlock()
 
-	w.WriteHeader(http.StatusOK)
-}
+	i := findRequest(data.ID)
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "отклик не найден")
+		return
+	}
+	if v, ok := requestVacancy(requests[i]); !ok || v.OrganizationID != data.Organization {
+		writeError(w, http.StatusNotFound, "отклик не найден")
+		return
+	}
+	// Отклики, задержанные или отклоненные модератором, лежат отдельно и сюда
+	// не попадают, поэтому решения ждет любой еще не одобренный отклик
+	if requests[i].Accept {
+		writeError(w, http.StatusConflict, "отклик уже одобрен")
+		return
+	}
+	req := requests[i]
 
-// 8. Apply Request - POST /JobService/hs/jobservice/applyrequest
-func applyRequest(w http.ResponseWriter, r *http.Request) {
-	logRequest(r)
+	text := data.Text
+	if data.Template != "" {
+		i := findTemplate(data.Template)
+		if i < 0 || notifyTemplates[i].Organization != data.Organization {
+			writeError(w, http.StatusNotFound, "шаблон не найден")
+			return
+		}
 
-	var data map[string]string
-	json.NewDecoder(r.Body).Decode(&data)
+		variables := defaultTemplateVariables(req)
+		for k, v := range data.Variables {
+			variables[k] = v
+		}
 
-	fmt.Printf("Номер отклика: %s, Сообщение: %s\n", data["number"], data["text"])
-	fmt.Println("✓ Отклик одобрен")
+		rendered, err := renderTemplate(notifyTemplates[i].Text, variables)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		text = rendered
+	}
+	if text == "" {
+		writeError(w, http.StatusBadRequest, "не указано сообщение кандидату")
+		return
+	}
+
+	notify := Notify{
+		Text:            text,
+		Date:            time.Now(),
+		NumberOfRequest: req.Number,
+		Student:         req.Student,
+	}
+	requests[i].Accept = true
+	addNotify(notify)
+	audit(r, accountLogin(data.Organization, ""), AuditRequestApply, req.ID, req, requests[i])
+
+	requestLogger(r).Info("отклик одобрен", "request", req.ID, "vacancy", req.Number)
 
 	w.WriteHeader(http.StatusOK)
 }
 
-// 9. Get Notifications - GET /JobService/hs/jobservice/mynotify
+// defaultTemplateVariables заполняет подстановки шаблона по данным отклика и вакансии
+func defaultTemplateVariables(req Request) map[string]string {
+	variables := map[string]string{"student": studentName(req.Student)}
+	if v, ok := requestVacancy(req); ok {
+		variables["title"] = v.Title
+		variables["datebegin"] = v.DateOfBegin.Format("02.01.2006")
+		variables["dateend"] = v.DateOfEnd.Format("02.01.2006")
+	}
+	return variables
+}
+
+// 9. Get Notifications - GET /JobService/hs/jobservice/mynotify/?student=Student
+// Работодатель получает свои уведомления по ?organization=GUID.
+// Уведомления без адресата (исходные данные) видны всем студентам.
 func getNotifications(w http.ResponseWriter, r *http.Request) {
 	logRequest(r)
 
 	student := r.URL.Query().Get("student")
-	fmt.Printf("СНИЛС студента: %s\n", student)
+	organization := r.URL.Query().Get("organization")
+	ok := false
+	if organization != "" {
+		organization, ok = requestOrganization(w, r, organization)
+	} else {
+		student, ok = requestStudent(w, r, student)
+	}
+	if !ok {
+		return
+	}
 
-	fmt.Printf("✓ Возвращены уведомления: %d шт.\n", len(notifies))
+	dataMu.RLock()
+	defer dataMu.RUnlock()
+
+	result := make([]Notify, 0, len(notifies))
+	for _, n := range notifies {
+		switch {
+		case organization != "":
+			if n.Organization == organization {
+				result = append(result, n)
+			}
+		case n.Student == student && student != "",
+			n.Student == "" && n.Organization == "":
+			result = append(result, n)
+		}
+	}
+
+	requestLogger(r).Debug("возвращены уведомления", "count", len(result))
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(notifies)
+	json.NewEncoder(w).Encode(result)
 }
 
 // 10. Get Vacancy From Notify - GET /JobService/hs/jobservice/vacancyfromnotify
@@ -553,7 +932,9 @@ func getVacancyFromNotify(w http.ResponseWriter, r *http.Request) {
 	logRequest(r)
 
 	numberOfRequest := r.URL.Query().Get("numberofrequest")
-	fmt.Printf("Номер отклика: %s\n", numberOfRequest)
+
+	dataMu.RLock()
+	defer dataMu.RUnlock()
 
 	var result []Vacancy
 	for _, v := range vacancies {
@@ -567,43 +948,220 @@ func getVacancyFromNotify(w http.ResponseWriter, r *http.Request) {
 		result = vacancies[:1]
 	}
 
-	fmt.Printf("✓ Вакансия найдена\n")
+	requestLogger(r).Debug("вакансия найдена", "vacancy", numberOfRequest)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(result)
 }
 
-// 11. Close Vacancy - POST /JobService/hs/jobservice/closevacancy
+// 11. Close Vacancy - POST /JobService/hs/jobservice/closevacancy/?number=Number&organization=GUID
+// Вакансия убирается из общего списка и сохраняется в closedVacancies.
 func closeVacancy(w http.ResponseWriter, r *http.Request) {
 	logRequest(r)
 
 	number := r.URL.Query().Get("number")
-	fmt.Printf("Вакансия закрыта: %s\n", number)
-	fmt.Println("✓ Вакансия удалена из списка")
+	organization, ok := requestOrganization(w, r, r.URL.Query().Get("organization"))
+	if !ok {
+		return
+	}
+	if organization == "" {
+		writeError(w, http.StatusUnauthorized, "не указана организация")
+		return
+	}
+
+	dataMu.Lock()
+	defer dataMu.Unlock()
+
+	i := slices.IndexFunc(vacancies, func(v Vacancy) bool { return v.Number == number })
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "вакансия не найдена")
+		return
+	}
+	v := vacancies[i]
+	if v.OrganizationID != organization {
+		writeError(w, http.StatusForbidden, "вакансия принадлежит другой организации")
+		return
+	}
+	closedVacancies[number] = v
+	vacancies = slices.Delete(vacancies, i, i+1)
+	vacancyIndex.Remove(number)
+	invalidateTagStats()
+	audit(r, accountLogin(v.OrganizationID, ""), AuditVacancyClose, number, v, nil)
+
+	requestLogger(r).Info("вакансия закрыта", "vacancy", number)
 
 	w.WriteHeader(http.StatusOK)
 }
 
 func main() {
-	fmt.Println("🚀 WhiteMustache Mock Server запущен")
-	fmt.Println("📍 http://localhost:80")
+	cfg, err := loadConfig(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		slog.Error("некорректные настройки", "error", err)
+		os.Exit(2)
+	}
+	if err := shared.SetupLogger(cfg.LogLevel, cfg.LogOutput); err != nil {
+		slog.Error("не удалось настроить журнал", "error", err)
+		os.Exit(1)
+	}
+	corsPolicy = cfg.CORS
+	rateLimits = cfg.RateLimits
+	csrfProtection = cfg.CSRF
+	if cfg.OIDC.Issuer != "" {
+		sso = newOIDCProvider(cfg.OIDC)
+	}
+	blobStore = FSBlobStore{Dir: filepath.Join(cfg.Storage, "uploads")}
+	if cfg.SeedFile != "" {
+		if err := loadSeed(cfg.SeedFile); err != nil {
+			slog.Error("не удалось загрузить начальные данные", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	accountProvider = LocalAccounts{Accounts: maps.Clone(accountsDB)}
+	if cfg.DevLDAP != "" {
+		// Встроенный каталог отдает учетные записи, загруженные выше
+		d := &devLDAP{entries: devLDAPDirectory(accountsDB), bindDN: cfg.LDAP.BindDN, bindPassword: cfg.LDAP.BindPassword}
+		if _, err := serveDevLDAP(cfg.DevLDAP, d); err != nil {
+			slog.Error("не удалось запустить встроенный каталог LDAP", "error", err)
+			os.Exit(1)
+		}
+		slog.Warn("запущен встроенный каталог LDAP, только для разработки", "addr", cfg.DevLDAP, "accounts", len(d.entries))
+	}
+	if cfg.LDAP.URL != "" {
+		// Встроенные учетные записи в каталоге не участвуют: пока каталог
+		// недоступен, войти нельзя
+		accountsDB = map[string]Account{}
+		accountProvider = NewLDAPAccounts(cfg.LDAP, nil)
+		if cfg.LDAP.SyncInterval > 0 {
+			startAccountSync(cfg.LDAP.SyncInterval)
+		}
+	}
 
 	mux := http.NewServeMux()
 
 	// Register routes
-	mux.HandleFunc("/JobService/hs/jobservice/vacancy", createVacancy)
-	mux.HandleFunc("/JobService/hs/jobservice/request", createRequest)
-	mux.HandleFunc("/JobService/hs/jobservice/vacancylist/", getVacancyList)
-	mux.HandleFunc("/JobService/hs/jobservice/tags", getTags)
-	mux.HandleFunc("/JobService/hs/jobservice/requestlist", getRequestList)
-	mux.HandleFunc("/JobService/hs/jobservice/requestlist/", getRequestList)
-	mux.HandleFunc("/JobService/hs/jobservice/checkaccount/", checkAccount)
-	mux.HandleFunc("/JobService/hs/jobservice/faq", sendFAQ)
-	mux.HandleFunc("/JobService/hs/jobservice/applyrequest", applyRequest)
-	mux.HandleFunc("/JobService/hs/jobservice/mynotify/", getNotifications)
-	mux.HandleFunc("/JobService/hs/jobservice/vacancyfromnotify/", getVacancyFromNotify)
-	mux.HandleFunc("/JobService/hs/jobservice/closevacancy/", closeVacancy)
-
-	handler := corsMiddleware(mux)
-
-	log.Fatal(http.ListenAndServe(":80", handler))
+	mux.HandleFunc("POST /JobService/hs/jobservice/vacancy", createVacancy)
+	mux.HandleFunc("POST /JobService/hs/jobservice/request", createRequest)
+	mux.HandleFunc("GET /JobService/hs/jobservice/vacancylist/", getVacancyList)
+	mux.HandleFunc("GET /JobService/hs/jobservice/tags", getTags)
+	mux.HandleFunc("GET /JobService/hs/jobservice/tags/", getTags)
+	mux.HandleFunc("GET /JobService/hs/jobservice/requestlist", getRequestList)
+	mux.HandleFunc("GET /JobService/hs/jobservice/requestlist/", getRequestList)
+	mux.HandleFunc("GET /JobService/hs/jobservice/checkaccount/", checkAccount)
+	mux.HandleFunc("GET /JobService/hs/jobservice/csrftoken/", csrfToken)
+	mux.HandleFunc("GET /JobService/hs/jobservice/sso/login/", ssoLogin)
+	mux.HandleFunc("GET /JobService/hs/jobservice/sso/callback/", ssoCallback)
+	mux.HandleFunc("GET /JobService/hs/jobservice/session/", getSession)
+	mux.HandleFunc("POST /JobService/hs/jobservice/logout/", logoutSession)
+	mux.HandleFunc("POST /JobService/hs/jobservice/faq", sendFAQ)
+	mux.HandleFunc("POST /JobService/hs/jobservice/applyrequest", applyRequest)
+	mux.HandleFunc("GET /JobService/hs/jobservice/mynotify/", getNotifications)
+	mux.HandleFunc("GET /JobService/hs/jobservice/vacancyfromnotify/", getVacancyFromNotify)
+	mux.HandleFunc("POST /JobService/hs/jobservice/closevacancy/", closeVacancy)
+	mux.HandleFunc("GET /JobService/hs/jobservice/templates/", getTemplates)
+	mux.HandleFunc("POST /JobService/hs/jobservice/template", createTemplate)
+	mux.HandleFunc("POST /JobService/hs/jobservice/updatetemplate/", updateTemplate)
+	mux.HandleFunc("POST /JobService/hs/jobservice/deletetemplate/", deleteTemplate)
+	mux.HandleFunc("GET /JobService/hs/jobservice/templateplaceholders", getTemplatePlaceholders)
+	mux.HandleFunc("GET /JobService/hs/jobservice/profile/", getProfile)
+	mux.HandleFunc("POST /JobService/hs/jobservice/profile/", saveProfile)
+	mux.HandleFunc("GET /JobService/hs/jobservice/organizationlist/", getOrganizationList)
+	mux.HandleFunc("GET /JobService/hs/jobservice/organization/", getOrganization)
+	mux.HandleFunc("GET /JobService/hs/jobservice/attachmentlink/", getAttachmentLink)
+	mux.HandleFunc("GET /JobService/hs/jobservice/attachment/", downloadAttachment)
+
+	mux.HandleFunc("GET /JobService/hs/jobservice/savedsearches/", getSavedSearches)
+	mux.HandleFunc("POST /JobService/hs/jobservice/savedsearch", createSavedSearch)
+	mux.HandleFunc("POST /JobService/hs/jobservice/deletesavedsearch/", deleteSavedSearch)
+
+	mux.HandleFunc("GET /JobService/hs/jobservice/favorites/", getFavorites)
+	mux.HandleFunc("POST /JobService/hs/jobservice/favorite/", addFavorite)
+	mux.HandleFunc("POST /JobService/hs/jobservice/deletefavorite/", deleteFavorite)
+	mux.HandleFunc("GET /JobService/hs/jobservice/recommendations/", getRecommendations)
+	mux.HandleFunc("POST /JobService/hs/jobservice/tag", createTag)
+	mux.HandleFunc("POST /JobService/hs/jobservice/updatetag/", updateTag)
+	mux.HandleFunc("POST /JobService/hs/jobservice/renametag/", renameTag)
+	mux.HandleFunc("POST /JobService/hs/jobservice/mergetag/", mergeTag)
+	mux.HandleFunc("POST /JobService/hs/jobservice/deletetag/", deleteTag)
+	mux.HandleFunc("GET /JobService/hs/jobservice/mytickets/", getMyTickets)
+	mux.HandleFunc("GET /JobService/hs/jobservice/tickets/", getTickets)
+	mux.HandleFunc("POST /JobService/hs/jobservice/assignticket/", assignTicket)
+	mux.HandleFunc("POST /JobService/hs/jobservice/replyticket/", replyTicket)
+	mux.HandleFunc("POST /JobService/hs/jobservice/closeticket/", closeTicket)
+	mux.HandleFunc("GET /JobService/hs/jobservice/moderationqueue/", getModerationQueue)
+	mux.HandleFunc("POST /JobService/hs/jobservice/approvevacancy/", approveVacancy)
+	mux.HandleFunc("POST /JobService/hs/jobservice/rejectvacancy/", rejectVacancy)
+	mux.HandleFunc("POST /JobService/hs/jobservice/takedownvacancy/", takeDownVacancy)
+	mux.HandleFunc("GET /JobService/hs/jobservice/myvacancies/", getOrganizationSubmissions)
+	mux.HandleFunc("GET /JobService/hs/jobservice/moderationlog/", getModerationLog)
+	mux.HandleFunc("GET /JobService/hs/jobservice/flaggedrequests/", getFlaggedRequests)
+	mux.HandleFunc("POST /JobService/hs/jobservice/approverequest/", approveRequest)
+	mux.HandleFunc("POST /JobService/hs/jobservice/rejectrequest/", rejectRequest)
+	mux.HandleFunc("GET /JobService/hs/jobservice/screeningrules/", screeningRulesHandler)
+	mux.HandleFunc("POST /JobService/hs/jobservice/screeningrules/", screeningRulesHandler)
+	mux.HandleFunc("GET /JobService/hs/jobservice/auditlog/", getAuditLog)
+	mux.HandleFunc("GET /metrics", serveMetrics)
+	mux.HandleFunc("GET /healthz", shared.Healthz)
+	mux.HandleFunc("GET /readyz", shared.Readyz(readinessChecks...))
+	mux.HandleFunc("GET /version", shared.Version(buildTime))
+
+	if err := loadScreeningConfig(screeningConfigFile); err != nil {
+		slog.Error("не удалось загрузить правила проверки текстов", "error", err)
+		os.Exit(1)
+	}
+	if err := openAuditLog(filepath.Join(cfg.Storage, auditLogFile)); err != nil {
+		slog.Error("не удалось открыть журнал аудита", "error", err)
+		os.Exit(1)
+	}
+	matcherDone := make(chan struct{})
+	go func() {
+		runSavedSearchMatcher()
+		close(matcherDone)
+	}()
+
+	var handler http.Handler = jsonContentTypeMiddleware(corsMiddleware(mux, bodyLimitMiddleware(rateLimitMiddleware(mux, csrfMiddleware(mux)))))
+	if cfg.DevIdP {
+		idp, err := newDevIdP(cfg.OIDC)
+		if err != nil {
+			slog.Error("не удалось запустить встроенный поставщик удостоверений", "error", err)
+			os.Exit(1)
+		}
+		root := http.NewServeMux()
+		root.Handle(idp.path+"/", idp.handler())
+		root.Handle("/", handler)
+		handler = root
+		slog.Warn("запущен встроенный поставщик удостоверений, только для разработки", "issuer", cfg.OIDC.Issuer)
+	}
+	servers, err := shared.NewServers(cfg.ServerConfig, handler, readTimeout, redactQuery)
+	if err != nil {
+		slog.Error("не удалось загрузить сертификат", "error", err)
+		os.Exit(1)
+	}
+	// Встроенный поставщик в режиме dev_tls работает по HTTPS с сертификатом,
+	// выпущенным выше
+	if cfg.DevTLS && (cfg.DevIdP || cfg.OIDC.Issuer != "") {
+		if err := trustDevCA(cfg.DevTLSDir); err != nil {
+			slog.Error("не удалось загрузить корневой сертификат разработки", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	slog.Info("WhiteMustache Mock Server запущен", "addr", cfg.Listen, "tls", servers[0].TLSConfig != nil, "redirect", cfg.RedirectListen)
+	err = shared.RunServers(func() {
+		// Новые запросы не принимаются: дорассылаем уведомления по подпискам
+		// и сбрасываем журнал аудита. Запросы, не завершившиеся к таймауту,
+		// в закрытую очередь уже ничего не добавят.
+		closePublishing()
+		<-matcherDone
+		if err := closeAuditLog(); err != nil {
+			slog.Error("не удалось сохранить журнал аудита", "error", err)
+		}
+	}, servers...)
+	if err != nil {
+		slog.Error("сервер остановлен", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("сервер остановлен")
 }
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"shared"
+)
+
+// Метрики отдаются на /metrics в текстовом формате Prometheus: счетчики
+// HTTP-запросов из shared и показатели сервиса вакансий.
+
+// Доменные счетчики
+var (
+	notificationsSent atomic.Uint64
+	loginSuccesses    atomic.Uint64
+	loginFailures     atomic.Uint64
+	loginLockouts     atomic.Uint64
+)
+
+// writeDomainMetrics выводит показатели сервиса вакансий
+func writeDomainMetrics(w io.Writer) {
+	dataMu.RLock()
+	now := time.Now()
+	open := 0
+	for _, v := range vacancies {
+		if isOpenVacancy(v, now) {
+			open++
+		}
+	}
+	pendingEmployer := 0
+	for _, req := range requests {
+		if !req.Accept {
+			pendingEmployer++
+		}
+	}
+	pendingModeration := 0
+	for _, f := range flaggedRequests {
+		if f.Status == ModerationPending {
+			pendingModeration++
+		}
+	}
+	pendingVacancies := 0
+	for _, m := range moderationQueue {
+		if m.Status == ModerationPending {
+			pendingVacancies++
+		}
+	}
+	dataMu.RUnlock()
+
+	shared.WriteMetricHeader(w, "jobservice_open_vacancies", "gauge", "Опубликованные вакансии, принимающие отклики.")
+	fmt.Fprintf(w, "jobservice_open_vacancies %d\n", open)
+
+	shared.WriteMetricHeader(w, "jobservice_pending_vacancies", "gauge", "Вакансии, ожидающие модерации.")
+	fmt.Fprintf(w, "jobservice_pending_vacancies %d\n", pendingVacancies)
+
+	shared.WriteMetricHeader(w, "jobservice_pending_applications", "gauge", "Отклики без решения: у работодателя или у модератора.")
+	fmt.Fprintf(w, "jobservice_pending_applications{stage=\"employer\"} %d\n", pendingEmployer)
+	fmt.Fprintf(w, "jobservice_pending_applications{stage=\"moderation\"} %d\n", pendingModeration)
+
+	shared.WriteMetricHeader(w, "jobservice_notifications_sent_total", "counter", "Уведомления, отправленные с момента запуска.")
+	fmt.Fprintf(w, "jobservice_notifications_sent_total %d\n", notificationsSent.Load())
+
+	shared.WriteMetricHeader(w, "jobservice_login_attempts_total", "counter", "Попытки входа через /checkaccount.")
+	fmt.Fprintf(w, "jobservice_login_attempts_total{result=\"success\"} %d\n", loginSuccesses.Load())
+	fmt.Fprintf(w, "jobservice_login_attempts_total{result=\"unknown_user\"} %d\n", loginFailures.Load())
+	fmt.Fprintf(w, "jobservice_login_attempts_total{result=\"locked\"} %d\n", loginLockouts.Load())
+}
+
+// serveMetrics - GET /metrics
+func serveMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	shared.WriteHTTPMetrics(w)
+	writeDomainMetrics(w)
+}
@@ -0,0 +1,478 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ModerationItem - вакансия, отправленная организацией на модерацию.
+// Вакансия попадает в общий список только после одобрения модератором.
+type ModerationItem struct {
+	Vacancy   Vacancy   `json:"Vacancy"`
+	Status    string    `json:"Status"`
+	Moderator string    `json:"Moderator"`
+	Reason    string    `json:"Reason"`
+	Submitted time.Time `json:"Submitted"`
+	Decided   time.Time `json:"Decided,omitzero"`
+	// Findings - нарушения, найденные автоматической проверкой текста
+	Findings []ScreeningFinding `json:"Findings,omitempty"`
+}
+
+// FlaggedRequest - отклик, задержанный автоматической проверкой текста.
+// Работодатель увидит отклик только после одобрения модератором.
+type FlaggedRequest struct {
+	ID        string             `json:"ID"`
+	Request   Request            `json:"Request"`
+	Findings  []ScreeningFinding `json:"Findings"`
+	Status    string             `json:"Status"`
+	Moderator string             `json:"Moderator"`
+	Reason    string             `json:"Reason"`
+	Submitted time.Time          `json:"Submitted"`
+	Decided   time.Time          `json:"Decided,omitzero"`
+}
+
+// ModerationAction - запись журнала действий модераторов
+type ModerationAction struct {
+	Date      time.Time `json:"Date"`
+	Moderator string    `json:"Moderator"`
+	Kind      string    `json:"Kind"`
+	Action    string    `json:"Action"`
+	Number    string    `json:"Number"`
+	Reason    string    `json:"Reason"`
+}
+
+// Статусы модерации вакансий
+const (
+	ModerationPending   = "pending"
+	ModerationApproved  = "approved"
+	ModerationRejected  = "rejected"
+	ModerationTakenDown = "takendown"
+)
+
+var (
+	moderationQueue    = []ModerationItem{}
+	moderationLog      = []ModerationAction{}
+	flaggedRequests    = []FlaggedRequest{}
+	lastFlaggedRequest = 0
+)
+
+// findModerationItem возвращает индекс вакансии в очереди модерации или -1
+func findModerationItem(number string) int {
+	return slices.IndexFunc(moderationQueue, func(m ModerationItem) bool { return m.Vacancy.Number == number })
+}
+
+// findFlaggedRequest возвращает индекс задержанного отклика или -1
+func findFlaggedRequest(id string) int {
+	return slices.IndexFunc(flaggedRequests, func(f FlaggedRequest) bool { return f.ID == id })
+}
+
+// moderationEnabled сообщает, может ли кто-то принимать решения модератора.
+// Права модераторов выдаются только по сессии, поэтому без входа через
+// «Мой Универ» очереди модерации некому разбирать, и они не используются.
+func moderationEnabled() bool {
+	return sso != nil
+}
+
+// submitVacancy проверяет текст новой вакансии и ставит ее в очередь модерации,
+// а без модерации сразу публикует. Возвращает false, если вакансия ждет
+// модератора. Вызывающий держит dataMu.
+func submitVacancy(v Vacancy, org Organization) bool {
+	findings := screen(ScreeningSubject{
+		Kind:         ScreeningVacancy,
+		Texts:        []string{v.Title, v.Description},
+		Salary:       v.Salary,
+		Organization: org,
+	})
+	if !moderationEnabled() {
+		vacancies = append(vacancies, v)
+		publishVacancy(v)
+		if len(findings) > 0 {
+			slog.Warn("вакансия опубликована без модерации", "vacancy", v.Number, "findings", len(findings))
+		}
+		return true
+	}
+	moderationQueue = append(moderationQueue, ModerationItem{
+		Vacancy:   v,
+		Status:    ModerationPending,
+		Submitted: time.Now(),
+		Findings:  findings,
+	})
+	slog.Info("вакансия ожидает модерации", "vacancy", v.Number, "findings", len(findings))
+	return false
+}
+
+// submitRequest проверяет текст отклика. Отклик без нарушений (или любой
+// отклик, если модерация не используется) сразу попадает к работодателю,
+// остальные задерживаются до решения модератора.
+// Возвращает false, если отклик задержан. Вызывающий держит dataMu.
+func submitRequest(req Request) bool {
+	findings := screen(ScreeningSubject{Kind: ScreeningRequest, Texts: []string{req.Description}})
+	if len(findings) == 0 || !moderationEnabled() {
+		if len(findings) > 0 {
+			slog.Warn("отклик передан работодателю без модерации", "vacancy", req.Number, "findings", len(findings))
+		}
+		requests = append(requests, req)
+		return true
+	}
+	lastFlaggedRequest++
+	flaggedRequests = append(flaggedRequests, FlaggedRequest{
+		ID:        formatNumber(lastFlaggedRequest),
+		Request:   req,
+		Findings:  findings,
+		Status:    ModerationPending,
+		Submitted: time.Now(),
+	})
+	slog.Info("отклик задержан до проверки модератором", "vacancy", req.Number, "findings", len(findings))
+	return false
+}
+
+// logModeration записывает действие модератора в журнал. kind - ScreeningVacancy
+// или ScreeningRequest. Вызывающий держит dataMu.
+func logModeration(moderator, kind, action, number, reason string) {
+	moderationLog = append(moderationLog, ModerationAction{
+		Date:      time.Now(),
+		Moderator: moderator,
+		Kind:      kind,
+		Action:    action,
+		Number:    number,
+		Reason:    reason,
+	})
+	slog.Info("решение модератора", "moderator", moderator, "kind", kind, "action", action, "number", number)
+}
+
+// notifyOrganization отправляет организации уведомление о решении модератора.
+// Вызывающий держит dataMu.
+func notifyOrganization(v Vacancy, text string) {
+	addNotify(Notify{
+		Text:         text,
+		Date:         time.Now(),
+		Organization: v.OrganizationID,
+	})
+}
+
+// readModerationReason читает причину решения из тела запроса
+func readModerationReason(r *http.Request) string {
+	var data struct {
+		Reason string `json:"reason"`
+	}
+	json.NewDecoder(r.Body).Decode(&data)
+	return strings.TrimSpace(data.Reason)
+}
+
+// 40. Get Moderation Queue - GET /JobService/hs/jobservice/moderationqueue/?status=pending&flagged=true
+// Без status возвращаются вакансии, ожидающие решения. flagged=true оставляет только
+// вакансии с нарушениями, найденными автоматической проверкой.
+func getModerationQueue(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	q := r.URL.Query()
+	if _, ok := requestModerator(w, r); !ok {
+		return
+	}
+	status := q.Get("status")
+	if status == "" {
+		status = ModerationPending
+	}
+	flaggedOnly := q.Get("flagged") == "true"
+
+	dataMu.RLock()
+	result := make([]ModerationItem, 0)
+	for _, m := range moderationQueue {
+		if m.Status == status && (!flaggedOnly || len(m.Findings) > 0) {
+			result = append(result, m)
+		}
+	}
+	dataMu.RUnlock()
+
+	requestLogger(r).Debug("возвращены вакансии на модерации", "count", len(result))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// 41. Approve Vacancy - POST /JobService/hs/jobservice/approvevacancy/?number=Number
+func approveVacancy(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	q := r.URL.Query()
+	moderator, ok := requestModerator(w, r)
+	if !ok {
+		return
+	}
+
+	dataMu.Lock()
+	defer dataMu.Unlock()
+
+	i := findModerationItem(q.Get("number"))
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "вакансия не найдена")
+		return
+	}
+	if moderationQueue[i].Status != ModerationPending {
+		writeError(w, http.StatusConflict, "решение по вакансии уже принято")
+		return
+	}
+	m := &moderationQueue[i]
+	m.Status = ModerationApproved
+	m.Moderator = moderator
+	m.Decided = time.Now()
+
+	vacancies = append(vacancies, m.Vacancy)
+	publishVacancy(m.Vacancy)
+	audit(r, moderator, AuditVacancyApprove, m.Vacancy.Number, nil, m.Vacancy)
+	logModeration(moderator, ScreeningVacancy, ModerationApproved, m.Vacancy.Number, "")
+	notifyOrganization(m.Vacancy, fmt.Sprintf("Вакансия №%s «%s» опубликована", m.Vacancy.Number, m.Vacancy.Title))
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(m)
+}
+
+// 42. Reject Vacancy - POST /JobService/hs/jobservice/rejectvacancy/?number=Number
+// Причина отказа передается в теле и отправляется организации в уведомлении.
+func rejectVacancy(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	q := r.URL.Query()
+	moderator, ok := requestModerator(w, r)
+	if !ok {
+		return
+	}
+	reason := readModerationReason(r)
+	if reason == "" {
+		writeError(w, http.StatusBadRequest, "не указана причина отказа")
+		return
+	}
+
+	dataMu.Lock()
+	defer dataMu.Unlock()
+
+	i := findModerationItem(q.Get("number"))
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "вакансия не найдена")
+		return
+	}
+	if moderationQueue[i].Status != ModerationPending {
+		writeError(w, http.StatusConflict, "решение по вакансии уже принято")
+		return
+	}
+	m := &moderationQueue[i]
+	m.Status = ModerationRejected
+	m.Moderator = moderator
+	m.Reason = reason
+	m.Decided = time.Now()
+
+	logModeration(moderator, ScreeningVacancy, ModerationRejected, m.Vacancy.Number, reason)
+	audit(r, moderator, AuditVacancyReject, m.Vacancy.Number, nil, m)
+	notifyOrganization(m.Vacancy, fmt.Sprintf("Вакансия №%s «%s» отклонена модератором: %s", m.Vacancy.Number, m.Vacancy.Title, reason))
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(m)
+}
+
+// 43. Take Down Vacancy - POST /JobService/hs/jobservice/takedownvacancy/?number=Number
+// Снимает опубликованную вакансию так же, как /closevacancy, и сообщает организации причину.
+func takeDownVacancy(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	q := r.URL.Query()
+	moderator, ok := requestModerator(w, r)
+	if !ok {
+		return
+	}
+	reason := readModerationReason(r)
+	if reason == "" {
+		writeError(w, http.StatusBadRequest, "не указана причина снятия")
+		return
+	}
+	number := q.Get("number")
+
+	dataMu.Lock()
+	defer dataMu.Unlock()
+
+	i := slices.IndexFunc(vacancies, func(v Vacancy) bool { return v.Number == number })
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "вакансия не найдена")
+		return
+	}
+	v := vacancies[i]
+	closedVacancies[number] = v
+	vacancies = slices.Delete(vacancies, i, i+1)
+	vacancyIndex.Remove(number)
+	invalidateTagStats()
+
+	// Исходные вакансии не проходили модерацию и добавляются в очередь только при снятии
+	j := findModerationItem(number)
+	if j < 0 {
+		moderationQueue = append(moderationQueue, ModerationItem{Vacancy: v, Submitted: v.DateOfDocument})
+		j = len(moderationQueue) - 1
+	}
+	item := &moderationQueue[j]
+	item.Status = ModerationTakenDown
+	item.Moderator = moderator
+	item.Reason = reason
+	item.Decided = time.Now()
+
+	logModeration(moderator, ScreeningVacancy, ModerationTakenDown, number, reason)
+	audit(r, moderator, AuditVacancyTakeDown, number, v, item)
+	notifyOrganization(v, fmt.Sprintf("Вакансия №%s «%s» снята модератором: %s", number, v.Title, reason))
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(item)
+}
+
+// 46. Get Flagged Requests - GET /JobService/hs/jobservice/flaggedrequests/?status=pending
+func getFlaggedRequests(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	q := r.URL.Query()
+	if _, ok := requestModerator(w, r); !ok {
+		return
+	}
+	status := q.Get("status")
+	if status == "" {
+		status = ModerationPending
+	}
+
+	dataMu.RLock()
+	result := make([]FlaggedRequest, 0)
+	for _, f := range flaggedRequests {
+		if f.Status == status {
+			result = append(result, f)
+		}
+	}
+	dataMu.RUnlock()
+
+	requestLogger(r).Debug("возвращены задержанные отклики", "count", len(result))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// 47. Approve Request - POST /JobService/hs/jobservice/approverequest/?id=ID
+func approveRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	q := r.URL.Query()
+	moderator, ok := requestModerator(w, r)
+	if !ok {
+		return
+	}
+
+	dataMu.Lock()
+	defer dataMu.Unlock()
+
+	i := findFlaggedRequest(q.Get("id"))
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "отклик не найден")
+		return
+	}
+	if flaggedRequests[i].Status != ModerationPending {
+		writeError(w, http.StatusConflict, "решение по отклику уже принято")
+		return
+	}
+	f := &flaggedRequests[i]
+	f.Status = ModerationApproved
+	f.Moderator = moderator
+	f.Decided = time.Now()
+	requests = append(requests, f.Request)
+	logModeration(moderator, ScreeningRequest, ModerationApproved, f.ID, "")
+	audit(r, moderator, AuditRequestApprove, f.ID, nil, f.Request)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(f)
+}
+
+// 48. Reject Request - POST /JobService/hs/jobservice/rejectrequest/?id=ID
+// Причина отказа передается в теле и отправляется студенту в уведомлении.
+func rejectRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	q := r.URL.Query()
+	moderator, ok := requestModerator(w, r)
+	if !ok {
+		return
+	}
+	reason := readModerationReason(r)
+	if reason == "" {
+		writeError(w, http.StatusBadRequest, "не указана причина отказа")
+		return
+	}
+
+	dataMu.Lock()
+	defer dataMu.Unlock()
+
+	i := findFlaggedRequest(q.Get("id"))
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "отклик не найден")
+		return
+	}
+	if flaggedRequests[i].Status != ModerationPending {
+		writeError(w, http.StatusConflict, "решение по отклику уже принято")
+		return
+	}
+	f := &flaggedRequests[i]
+	f.Status = ModerationRejected
+	f.Moderator = moderator
+	f.Reason = reason
+	f.Decided = time.Now()
+	// Отклоненный отклик не попадет к работодателю, его файл больше не нужен
+	if id := f.Request.Attachment; id != "" {
+		delete(attachments, id)
+		deleteAttachmentFile(id)
+	}
+	logModeration(moderator, ScreeningRequest, ModerationRejected, f.ID, reason)
+	audit(r, moderator, AuditRequestReject, f.ID, nil, f)
+	addNotify(Notify{
+		Text:            "Отклик отклонен модератором: " + reason,
+		Date:            time.Now(),
+		NumberOfRequest: f.Request.Number,
+		Student:         f.Request.Student,
+	})
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(f)
+}
+
+// 44. Get Organization Submissions - GET /JobService/hs/jobservice/myvacancies/?organization=GUID
+// Организация видит статус модерации своих вакансий и причину отказа.
+func getOrganizationSubmissions(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	organization, ok := requestOrganization(w, r, r.URL.Query().Get("organization"))
+	if !ok {
+		return
+	}
+
+	dataMu.RLock()
+	result := make([]ModerationItem, 0)
+	for _, m := range moderationQueue {
+		if m.Vacancy.OrganizationID == organization {
+			result = append(result, m)
+		}
+	}
+	dataMu.RUnlock()
+
+	requestLogger(r).Debug("возвращены вакансии организации", "count", len(result))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// 45. Get Moderation Log - GET /JobService/hs/jobservice/moderationlog/
+func getModerationLog(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	if _, ok := requestAdmin(w, r); !ok {
+		return
+	}
+
+	dataMu.RLock()
+	result := slices.Clone(moderationLog)
+	dataMu.RUnlock()
+
+	requestLogger(r).Debug("возвращен журнал модерации", "count", len(result))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
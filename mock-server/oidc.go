@@ -0,0 +1,558 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"shared"
+)
+
+// Вход через единую учетную запись университета (OpenID Connect, поток
+// authorization code с PKCE). Страница отправляет браузер на /sso/login,
+// сервер перенаправляет его к поставщику удостоверений, а после входа
+// получает в /sso/callback код, обменивает его на ID-токен, проверяет
+// подпись и создает сессию в cookie. Затем браузер возвращается на страницу,
+// и она узнает пользователя через /session.
+
+// OIDCConfig - настройки входа через поставщика удостоверений
+type OIDCConfig struct {
+	// Issuer - адрес поставщика, пустой - вход через него выключен
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL - адрес /sso/callback, зарегистрированный у поставщика
+	RedirectURL string
+	// PostLoginURL - страница, на которую браузер возвращается после входа
+	PostLoginURL string
+}
+
+const (
+	// ssoLoginTimeout - сколько ждать возвращения пользователя от поставщика
+	ssoLoginTimeout = 10 * time.Minute
+	sessionTTL      = 8 * time.Hour
+	sessionCookie   = "session"
+	// ssoClockSkew - допустимое расхождение часов с поставщиком
+	ssoClockSkew = time.Minute
+)
+
+// ssoClient - HTTP-клиент для запросов к поставщику
+var ssoClient = &http.Client{Timeout: 10 * time.Second}
+
+// trustDevCA добавляет корневой сертификат разработки к доверенным для
+// запросов к поставщику: встроенный поставщик в режиме dev_tls работает по HTTPS
+func trustDevCA(dir string) error {
+	transport, err := shared.DevCATransport(dir)
+	if err != nil {
+		return err
+	}
+	ssoClient.Transport = transport
+	return nil
+}
+
+// oidcDiscovery - нужная часть /.well-known/openid-configuration
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type pendingLogin struct {
+	nonce    string
+	verifier string
+	expires  time.Time
+}
+
+// sso - поставщик удостоверений, nil - вход через него выключен
+var sso *oidcProvider
+
+type oidcProvider struct {
+	cfg OIDCConfig
+
+	// mu защищает сведения о поставщике и его ключи, но не запросы к нему.
+	// Они загружаются при первом входе: встроенный поставщик при запуске
+	// сервера еще не отвечает.
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	keys      map[string]*rsa.PublicKey
+
+	// pending - начатые входы по state
+	pending struct {
+		sync.Mutex
+		m map[string]pendingLogin
+	}
+}
+
+func newOIDCProvider(cfg OIDCConfig) *oidcProvider {
+	p := &oidcProvider{cfg: cfg}
+	p.pending.m = map[string]pendingLogin{}
+	return p
+}
+
+// getJSON запрашивает у поставщика JSON-документ
+func getJSON(u string, v any) error {
+	resp, err := ssoClient.Get(u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: код ответа %d", u, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (p *oidcProvider) config() (*oidcDiscovery, error) {
+	p.mu.Lock()
+	d := p.discovery
+	p.mu.Unlock()
+	if d != nil {
+		return d, nil
+	}
+
+	d = &oidcDiscovery{}
+	if err := getJSON(p.cfg.Issuer+"/.well-known/openid-configuration", d); err != nil {
+		return nil, err
+	}
+	if d.Issuer != p.cfg.Issuer {
+		return nil, fmt.Errorf("поставщик назвался %q вместо %q", d.Issuer, p.cfg.Issuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, errors.New("в описании поставщика нет адресов входа, токенов или ключей")
+	}
+	p.mu.Lock()
+	p.discovery = d
+	p.mu.Unlock()
+	return d, nil
+}
+
+// jwk - открытый ключ RSA в формате JWK
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func (k jwk) publicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+	exp := new(big.Int).SetBytes(e)
+	if !exp.IsInt64() || exp.Int64() > 1<<31-1 {
+		return nil, errors.New("некорректная экспонента ключа")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+}
+
+// key возвращает ключ подписи по идентификатору. Неизвестный ключ -
+// повод перечитать набор: поставщик мог сменить ключи.
+func (p *oidcProvider) key(kid string) (*rsa.PublicKey, error) {
+	d, err := p.config()
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	k, ok := p.keys[kid]
+	p.mu.Unlock()
+	if ok {
+		return k, nil
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := getJSON(d.JWKSURI, &set); err != nil {
+		return nil, err
+	}
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		if pub, err := k.publicKey(); err == nil {
+			keys[k.Kid] = pub
+		}
+	}
+	p.mu.Lock()
+	p.keys = keys
+	p.mu.Unlock()
+	k, ok = keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("неизвестный ключ подписи %q", kid)
+	}
+	return k, nil
+}
+
+// audience - утверждение aud: строка или список строк
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		*a = audience{s}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+// idTokenClaims - утверждения ID-токена
+type idTokenClaims struct {
+	Issuer            string   `json:"iss"`
+	Subject           string   `json:"sub"`
+	Audience          audience `json:"aud"`
+	Expiry            int64    `json:"exp"`
+	IssuedAt          int64    `json:"iat"`
+	Nonce             string   `json:"nonce"`
+	PreferredUsername string   `json:"preferred_username"`
+	// Роли на платформе. Если поставщик их не передает,
+	// они берутся из учетной записи с тем же логином.
+	Student      string `json:"student_id"`
+	Organization string `json:"organization"`
+	Role         string `json:"role"`
+}
+
+// verifyIDToken проверяет подпись RS256 и утверждения ID-токена
+func (p *oidcProvider) verifyIDToken(token, nonce string, now time.Time) (idTokenClaims, error) {
+	var claims idTokenClaims
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return claims, errors.New("ID-токен не в формате JWT")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return claims, err
+	}
+	if header.Alg != "RS256" {
+		return claims, fmt.Errorf("алгоритм подписи %q не поддерживается", header.Alg)
+	}
+	key, err := p.key(header.Kid)
+	if err != nil {
+		return claims, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return claims, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+		return claims, errors.New("неверная подпись ID-токена")
+	}
+
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return claims, err
+	}
+	switch {
+	case claims.Issuer != p.cfg.Issuer:
+		return claims, fmt.Errorf("токен выдан %q", claims.Issuer)
+	case !slices.Contains(claims.Audience, p.cfg.ClientID):
+		return claims, errors.New("токен выдан другому клиенту")
+	case now.After(time.Unix(claims.Expiry, 0).Add(ssoClockSkew)):
+		return claims, errors.New("срок действия токена истек")
+	case time.Unix(claims.IssuedAt, 0).After(now.Add(ssoClockSkew)):
+		return claims, errors.New("токен выдан в будущем")
+	case subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1:
+		return claims, errors.New("nonce не совпадает")
+	case claims.Subject == "":
+		return claims, errors.New("в токене нет sub")
+	}
+	return claims, nil
+}
+
+func decodeJWTPart(part string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// exchangeCode обменивает код авторизации на ID-токен
+func (p *oidcProvider) exchangeCode(code, verifier string) (string, error) {
+	d, err := p.config()
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"code_verifier": {verifier},
+		"client_id":     {p.cfg.ClientID},
+	}
+	req, err := http.NewRequest(http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+	resp, err := ssoClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("ответ на обмен кода: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || body.IDToken == "" {
+		return "", fmt.Errorf("поставщик отказал в обмене кода: %s %s", body.Error, body.ErrorDescription)
+	}
+	return body.IDToken, nil
+}
+
+// claimsAccount сопоставляет утверждения токена ролям платформы. Роли из
+// токена важнее записей accountsDB: справочник университета актуальнее.
+func claimsAccount(ctx context.Context, c idTokenClaims) (string, Account) {
+	login := c.PreferredUsername
+	if login == "" {
+		login = c.Subject
+	}
+	account, _ := lookupAccount(ctx, login)
+	if c.Student != "" {
+		account.Student = c.Student
+	}
+	if c.Organization != "" {
+		account.Organization = c.Organization
+	}
+	if c.Role != "" {
+		account.Role = c.Role
+	}
+	return login, account
+}
+
+// pkceChallenge возвращает code_challenge для метода S256
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Session - вход пользователя через поставщика
+type Session struct {
+	Login   string
+	Account Account
+	Expires time.Time
+}
+
+var sessions struct {
+	sync.Mutex
+	m map[string]Session
+}
+
+func createSession(login string, account Account, now time.Time) string {
+	sessions.Lock()
+	defer sessions.Unlock()
+	if sessions.m == nil {
+		sessions.m = map[string]Session{}
+	}
+	for id, s := range sessions.m {
+		if now.After(s.Expires) {
+			delete(sessions.m, id)
+		}
+	}
+	id := randomToken()
+	sessions.m[id] = Session{Login: login, Account: account, Expires: now.Add(sessionTTL)}
+	return id
+}
+
+// requestSession возвращает сессию из cookie запроса
+func requestSession(r *http.Request) (Session, bool) {
+	c, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return Session{}, false
+	}
+	sessions.Lock()
+	defer sessions.Unlock()
+	s, ok := sessions.m[c.Value]
+	if !ok || time.Now().After(s.Expires) {
+		return Session{}, false
+	}
+	return s, true
+}
+
+func setSessionCookie(w http.ResponseWriter, r *http.Request, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		// Lax, а не Strict: cookie выдается при возврате от поставщика,
+		// то есть в ответ на переход с другого сайта
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// ssoReturn возвращает браузер на страницу входа с результатом в параметре sso
+func ssoReturn(w http.ResponseWriter, r *http.Request, result string) {
+	u, _ := url.Parse(sso.cfg.PostLoginURL)
+	q := u.Query()
+	q.Set("sso", result)
+	u.RawQuery = q.Encode()
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+// 52. SSO Login - GET /JobService/hs/jobservice/sso/login
+func ssoLogin(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	if sso == nil {
+		writeError(w, http.StatusNotFound, "вход через поставщика удостоверений не настроен")
+		return
+	}
+	d, err := sso.config()
+	if err != nil {
+		requestLogger(r).Error("поставщик удостоверений недоступен", "error", err)
+		writeError(w, http.StatusBadGateway, "поставщик удостоверений недоступен")
+		return
+	}
+
+	state, nonce, verifier := randomToken(), randomToken(), randomToken()
+	now := time.Now()
+	sso.pending.Lock()
+	for s, p := range sso.pending.m {
+		if now.After(p.expires) {
+			delete(sso.pending.m, s)
+		}
+	}
+	sso.pending.m[state] = pendingLogin{nonce: nonce, verifier: verifier, expires: now.Add(ssoLoginTimeout)}
+	sso.pending.Unlock()
+
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {sso.cfg.ClientID},
+		"redirect_uri":          {sso.cfg.RedirectURL},
+		"scope":                 {"openid profile"},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {pkceChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	http.Redirect(w, r, d.AuthorizationEndpoint+sep+q.Encode(), http.StatusFound)
+}
+
+// 53. SSO Callback - GET /JobService/hs/jobservice/sso/callback/?code=Code&state=State
+func ssoCallback(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	if sso == nil {
+		writeError(w, http.StatusNotFound, "вход через поставщика удостоверений не настроен")
+		return
+	}
+	q := r.URL.Query()
+	state := q.Get("state")
+	sso.pending.Lock()
+	p, ok := sso.pending.m[state]
+	delete(sso.pending.m, state)
+	sso.pending.Unlock()
+
+	log := requestLogger(r)
+	now := time.Now()
+	switch {
+	case q.Get("error") != "":
+		log.Warn("поставщик отказал во входе", "error", q.Get("error"))
+		ssoReturn(w, r, "denied")
+		return
+	case state == "" || !ok || now.After(p.expires):
+		log.Warn("вход через поставщика с неизвестным или устаревшим state")
+		ssoReturn(w, r, "expired")
+		return
+	}
+
+	token, err := sso.exchangeCode(q.Get("code"), p.verifier)
+	if err != nil {
+		log.Error("не удалось получить ID-токен", "error", err)
+		ssoReturn(w, r, "error")
+		return
+	}
+	claims, err := sso.verifyIDToken(token, p.nonce, now)
+	if err != nil {
+		log.Error("ID-токен не прошел проверку", "error", err)
+		ssoReturn(w, r, "error")
+		return
+	}
+
+	login, account := claimsAccount(r.Context(), claims)
+	if account.Student == "" && account.Organization == "" && account.Role == "" {
+		loginFailures.Add(1)
+		log.Warn("вход через поставщика: пользователь не зарегистрирован на платформе")
+		ssoReturn(w, r, "unknown")
+		return
+	}
+	loginSuccesses.Add(1)
+	audit(r, login, AuditLogin, login, nil, nil)
+	setSessionCookie(w, r, createSession(login, account, now), int(sessionTTL.Seconds()))
+	log.Info("вход через поставщика удостоверений", "role", accountRole(account))
+	ssoReturn(w, r, "ok")
+}
+
+// SessionInfo - пользователь текущей сессии
+type SessionInfo struct {
+	Login string `json:"Login"`
+	Account
+}
+
+// 54. Get Session - GET /JobService/hs/jobservice/session
+func getSession(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	s, ok := requestSession(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "вход не выполнен")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(SessionInfo{Login: s.Login, Account: s.Account})
+}
+
+// 55. Logout - POST /JobService/hs/jobservice/logout
+func logoutSession(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	if c, err := r.Cookie(sessionCookie); err == nil {
+		sessions.Lock()
+		delete(sessions.m, c.Value)
+		sessions.Unlock()
+	}
+	setSessionCookie(w, r, "", -1)
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testIdP - встроенный поставщик на свободном порту. Поставщика за этим
+// адресом можно подменить, чтобы сменить ключи.
+type testIdP struct {
+	*devIdP
+	serving atomic.Pointer[devIdP]
+}
+
+// startTestIdP запускает встроенный поставщик и включает вход через него
+// до конца теста
+func startTestIdP(t *testing.T) *testIdP {
+	t.Helper()
+	idp := &testIdP{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idp.serving.Load().handler().ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg := OIDCConfig{
+		Issuer:       srv.URL + "/devidp",
+		ClientID:     "jobservice",
+		ClientSecret: "s3cret",
+		RedirectURL:  "http://mock.test/JobService/hs/jobservice/sso/callback/",
+		PostLoginURL: "http://pages.test/main.html",
+	}
+	idp.devIdP = newTestDevIdP(t, cfg)
+	idp.serving.Store(idp.devIdP)
+
+	provider := sso
+	sso = newOIDCProvider(cfg)
+	t.Cleanup(func() { sso = provider })
+	return idp
+}
+
+func newTestDevIdP(t *testing.T, cfg OIDCConfig) *devIdP {
+	t.Helper()
+	p, err := newDevIdP(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// ssoStart начинает вход и возвращает адрес формы поставщика
+func ssoStart(t *testing.T) *url.URL {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	ssoLogin(rec, httptest.NewRequest(http.MethodGet, "/JobService/hs/jobservice/sso/login", nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("sso/login: code %d: %s", rec.Code, rec.Body)
+	}
+	u, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+// idpAuthorize отправляет форму входа поставщика и возвращает параметры,
+// с которыми он перенаправил браузер в /sso/callback
+func idpAuthorize(t *testing.T, authorize *url.URL, login string) url.Values {
+	t.Helper()
+	form := authorize.Query()
+	form.Set("login", login)
+	form.Set("password", devIdPPassword)
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	endpoint := *authorize
+	endpoint.RawQuery = ""
+	resp, err := client.PostForm(endpoint.String(), form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize: code %d", resp.StatusCode)
+	}
+	u, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(u.String(), sso.cfg.RedirectURL) {
+		t.Fatalf("authorize redirected to %s", u)
+	}
+	return u.Query()
+}
+
+// ssoFinish вызывает /sso/callback и возвращает результат входа из
+// параметра sso и ответ
+func ssoFinish(t *testing.T, q url.Values) (string, *httptest.ResponseRecorder) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	ssoCallback(rec, httptest.NewRequest(http.MethodGet, "/JobService/hs/jobservice/sso/callback/?"+q.Encode(), nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("sso/callback: code %d: %s", rec.Code, rec.Body)
+	}
+	u, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Query().Get("sso"), rec
+}
+
+// editPending меняет начатый вход, как если бы его параметры подменили
+func editPending(t *testing.T, state string, edit func(*pendingLogin)) {
+	t.Helper()
+	sso.pending.Lock()
+	defer sso.pending.Unlock()
+	p, ok := sso.pending.m[state]
+	if !ok {
+		t.Fatalf("no pending login for state %q", state)
+	}
+	edit(&p)
+	sso.pending.m[state] = p
+}
+
+func TestSSOLogin(t *testing.T) {
+	startTestIdP(t)
+
+	authorize := ssoStart(t)
+	aq := authorize.Query()
+	if aq.Get("code_challenge_method") != "S256" || aq.Get("code_challenge") == "" || aq.Get("nonce") == "" {
+		t.Errorf("authorization request without PKCE or nonce: %s", authorize)
+	}
+	result, rec := ssoFinish(t, idpAuthorize(t, authorize, "ivanov.ii"))
+	if result != "ok" {
+		t.Fatalf("sso = %q, want ok", result)
+	}
+
+	var cookie *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == sessionCookie {
+			cookie = c
+		}
+	}
+	if cookie == nil || !cookie.HttpOnly {
+		t.Fatalf("session cookie = %+v", cookie)
+	}
+	r := httptest.NewRequest(http.MethodGet, "/JobService/hs/jobservice/session", nil)
+	r.AddCookie(cookie)
+	s, ok := requestSession(r)
+	if !ok || s.Login != "ivanov.ii" || s.Account.Student != "123-694-775 67" {
+		t.Errorf("session = %+v, %v", s, ok)
+	}
+}
+
+func TestSSOStateMismatch(t *testing.T) {
+	startTestIdP(t)
+
+	q := idpAuthorize(t, ssoStart(t), "ivanov.ii")
+	state := q.Get("state")
+	q.Set("state", state+"x")
+	if result, _ := ssoFinish(t, q); result != "expired" {
+		t.Errorf("unknown state: sso = %q, want expired", result)
+	}
+
+	// Без state вход не завершается, даже если код верный
+	q.Del("state")
+	if result, _ := ssoFinish(t, q); result != "expired" {
+		t.Errorf("missing state: sso = %q, want expired", result)
+	}
+
+	// Устаревший вход
+	q = idpAuthorize(t, ssoStart(t), "ivanov.ii")
+	editPending(t, q.Get("state"), func(p *pendingLogin) { p.expires = time.Now().Add(-time.Second) })
+	if result, _ := ssoFinish(t, q); result != "expired" {
+		t.Errorf("expired state: sso = %q, want expired", result)
+	}
+
+	// state одноразовый
+	q = idpAuthorize(t, ssoStart(t), "ivanov.ii")
+	if result, _ := ssoFinish(t, q); result != "ok" {
+		t.Fatalf("sso = %q, want ok", result)
+	}
+	if result, _ := ssoFinish(t, q); result != "expired" {
+		t.Errorf("replayed state: sso = %q, want expired", result)
+	}
+}
+
+func TestSSONonceMismatch(t *testing.T) {
+	startTestIdP(t)
+
+	q := idpAuthorize(t, ssoStart(t), "ivanov.ii")
+	editPending(t, q.Get("state"), func(p *pendingLogin) { p.nonce = randomToken() })
+	if result, rec := ssoFinish(t, q); result != "error" || rec.Header().Get("Set-Cookie") != "" {
+		t.Errorf("nonce mismatch: sso = %q, Set-Cookie %q", result, rec.Header().Get("Set-Cookie"))
+	}
+}
+
+func TestSSOPKCEMismatch(t *testing.T) {
+	startTestIdP(t)
+
+	q := idpAuthorize(t, ssoStart(t), "ivanov.ii")
+	editPending(t, q.Get("state"), func(p *pendingLogin) { p.verifier = randomToken() })
+	if result, _ := ssoFinish(t, q); result != "error" {
+		t.Errorf("verifier mismatch: sso = %q, want error", result)
+	}
+
+	// Код одноразовый: после неудачного обмена его нельзя использовать
+	// и с верным verifier
+	q = idpAuthorize(t, ssoStart(t), "ivanov.ii")
+	var verifier string
+	editPending(t, q.Get("state"), func(p *pendingLogin) { verifier = p.verifier })
+	if _, err := sso.exchangeCode(q.Get("code"), randomToken()); err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Errorf("exchangeCode with a wrong verifier: err = %v, want invalid_grant", err)
+	}
+	if _, err := sso.exchangeCode(q.Get("code"), verifier); err == nil {
+		t.Error("exchangeCode reused a code")
+	}
+}
+
+// testClaims - утверждения токена, который поставщик выдал бы ivanov.ii
+func testClaims(idp *devIdP, nonce string, now time.Time) map[string]any {
+	return map[string]any{
+		"iss":                idp.cfg.Issuer,
+		"sub":                "ivanov.ii",
+		"aud":                idp.cfg.ClientID,
+		"iat":                now.Unix(),
+		"exp":                now.Add(devIdPTokenTTL).Unix(),
+		"nonce":              nonce,
+		"preferred_username": "ivanov.ii",
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	idp := startTestIdP(t)
+	now := time.Now()
+	nonce := randomToken()
+
+	tests := []struct {
+		name  string
+		edit  func(map[string]any)
+		nonce string
+		want  string
+	}{
+		{name: "valid", nonce: nonce},
+		{name: "expired", nonce: nonce, want: "срок действия",
+			edit: func(c map[string]any) { c["exp"] = now.Add(-2 * ssoClockSkew).Unix() }},
+		{name: "expired within skew", nonce: nonce,
+			edit: func(c map[string]any) { c["exp"] = now.Add(-ssoClockSkew / 2).Unix() }},
+		{name: "issued in future", nonce: nonce, want: "в будущем",
+			edit: func(c map[string]any) { c["iat"] = now.Add(2 * ssoClockSkew).Unix() }},
+		{name: "nonce mismatch", nonce: randomToken(), want: "nonce"},
+		{name: "no nonce", nonce: nonce, want: "nonce",
+			edit: func(c map[string]any) { delete(c, "nonce") }},
+		{name: "other issuer", nonce: nonce, want: "выдан",
+			edit: func(c map[string]any) { c["iss"] = "https://evil.test" }},
+		{name: "other audience", nonce: nonce, want: "другому клиенту",
+			edit: func(c map[string]any) { c["aud"] = []string{"other"} }},
+		{name: "audience list", nonce: nonce,
+			edit: func(c map[string]any) { c["aud"] = []string{"other", idp.cfg.ClientID} }},
+		{name: "no subject", nonce: nonce, want: "sub",
+			edit: func(c map[string]any) { delete(c, "sub") }},
+	}
+	for _, tt := range tests {
+		claims := testClaims(idp.devIdP, nonce, now)
+		if tt.edit != nil {
+			tt.edit(claims)
+		}
+		token, err := idp.sign(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = sso.verifyIDToken(token, tt.nonce, now)
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestVerifyIDTokenSignature(t *testing.T) {
+	idp := startTestIdP(t)
+	now := time.Now()
+	nonce := randomToken()
+	token, err := idp.sign(testClaims(idp.devIdP, nonce, now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(token, ".")
+
+	// Другой ключ с тем же kid
+	forger := newTestDevIdP(t, idp.cfg)
+	forger.kid = idp.kid
+	forged, err := forger.sign(testClaims(idp.devIdP, nonce, now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Неизвестный поставщику ключ
+	stranger := newTestDevIdP(t, idp.cfg)
+	unknown, err := stranger.sign(testClaims(idp.devIdP, nonce, now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Утверждения изменены после подписи
+	claims := testClaims(idp.devIdP, nonce, now)
+	claims["role"] = RoleAdmin
+	other, err := idp.sign(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := parts[0] + "." + strings.Split(other, ".")[1] + "." + parts[2]
+	// Токен без подписи
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"`+idp.kid+`"}`)) + "." + parts[1] + "."
+
+	tests := []struct {
+		name, token, want string
+	}{
+		{"other key", forged, "неверная подпись"},
+		{"unknown kid", unknown, "неизвестный ключ"},
+		{"tampered claims", tampered, "неверная подпись"},
+		{"alg none", none, "не поддерживается"},
+		{"not a JWT", parts[0] + "." + parts[1], "JWT"},
+	}
+	for _, tt := range tests {
+		_, err := sso.verifyIDToken(tt.token, nonce, now)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+	if _, err := sso.verifyIDToken(token, nonce, now); err != nil {
+		t.Errorf("valid token after failures: %v", err)
+	}
+}
+
+func TestVerifyIDTokenKeyRotation(t *testing.T) {
+	idp := startTestIdP(t)
+	now := time.Now()
+	nonce := randomToken()
+
+	old, err := idp.sign(testClaims(idp.devIdP, nonce, now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sso.verifyIDToken(old, nonce, now); err != nil {
+		t.Fatal(err)
+	}
+
+	// Поставщик сменил ключ: неизвестный kid перечитывает набор ключей
+	rotated := newTestDevIdP(t, idp.cfg)
+	idp.serving.Store(rotated)
+	token, err := rotated.sign(testClaims(idp.devIdP, nonce, now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sso.verifyIDToken(token, nonce, now); err != nil {
+		t.Errorf("token signed with the new key: %v", err)
+	}
+	if _, err := sso.verifyIDToken(old, nonce, now); err == nil {
+		t.Error("token signed with the retired key was accepted")
+	}
+}
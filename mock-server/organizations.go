@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Organization - работодатель. ID совпадает с GUID организации в аккаунте (Account.Organization).
+type Organization struct {
+	ID           string               `json:"ID"`
+	Name         string               `json:"Name"`
+	Description  string               `json:"Description"`
+	Contacts     OrganizationContacts `json:"Contacts"`
+	LogoURL      string               `json:"LogoURL"`
+	Verification string               `json:"Verification"`
+	// Commercial - коммерческая организация; у нее не может быть неоплачиваемых вакансий
+	Commercial bool `json:"Commercial"`
+}
+
+// OrganizationContacts - контакты организации для студентов
+type OrganizationContacts struct {
+	Email   string `json:"Email"`
+	Phone   string `json:"Phone"`
+	Website string `json:"Website"`
+	Address string `json:"Address"`
+}
+
+// Статусы проверки организации университетом
+const (
+	VerificationNone     = "unverified"
+	VerificationPending  = "pending"
+	VerificationVerified = "verified"
+	VerificationRejected = "rejected"
+)
+
+var organizations = map[string]Organization{
+	"b3c1d2e4-0001-11f0-ae42-38d57ae2c1c1": {
+		ID:           "b3c1d2e4-0001-11f0-ae42-38d57ae2c1c1",
+		Name:         "Волонтеры ДВФУ",
+		Description:  "Волонтерский центр университета: социальные и экологические проекты",
+		Contacts:     OrganizationContacts{Email: "volunteers@dvfu.ru", Address: "о. Русский, п. Аякс, 10, корпус A"},
+		Verification: VerificationVerified,
+	},
+	"f2742040-cdb4-11f0-ae42-38d57ae2c1c1": {
+		ID:           "f2742040-cdb4-11f0-ae42-38d57ae2c1c1",
+		Name:         "CODE WORK",
+		Description:  "Школа программирования для студентов и школьников",
+		Contacts:     OrganizationContacts{Email: "hello@codework.ru", Website: "https://codework.ru"},
+		LogoURL:      "https://codework.ru/logo.svg",
+		Verification: VerificationVerified,
+		Commercial:   true,
+	},
+	"4c09ed30-cdb6-11f0-ae42-38d57ae2c1c1": {
+		ID:           "4c09ed30-cdb6-11f0-ae42-38d57ae2c1c1",
+		Name:         "Tech Startup",
+		Description:  "Стартап, разрабатывающий веб-сервисы для логистики",
+		Contacts:     OrganizationContacts{Email: "jobs@techstartup.ru", Phone: "+7 423 200-00-00"},
+		Verification: VerificationVerified,
+		Commercial:   true,
+	},
+	"b3c1d2e4-0002-11f0-ae42-38d57ae2c1c1": {
+		ID:           "b3c1d2e4-0002-11f0-ae42-38d57ae2c1c1",
+		Name:         "DVFU Research Lab",
+		Description:  "Научная лаборатория ДВФУ в области искусственного интеллекта",
+		Contacts:     OrganizationContacts{Email: "ailab@dvfu.ru", Address: "о. Русский, п. Аякс, 10, корпус D"},
+		Verification: VerificationVerified,
+	},
+	"7a8b9c0d-1e2f-3a4b-5c6d-7e8f9a0b1c2d": {
+		ID:           "7a8b9c0d-1e2f-3a4b-5c6d-7e8f9a0b1c2d",
+		Name:         "Hospital №1",
+		Description:  "Городская больница №1 г. Владивостока",
+		Contacts:     OrganizationContacts{Phone: "+7 423 245-00-00", Address: "ул. Садовая, 22"},
+		Verification: VerificationVerified,
+	},
+	"b3c1d2e4-0003-11f0-ae42-38d57ae2c1c1": {
+		ID:           "b3c1d2e4-0003-11f0-ae42-38d57ae2c1c1",
+		Name:         "Vladivostok Creative Studio",
+		Description:  "Дизайн-студия: брендинг, иллюстрация, веб-дизайн",
+		Contacts:     OrganizationContacts{Email: "studio@vcs.ru"},
+		Verification: VerificationPending,
+		Commercial:   true,
+	},
+	"b3c1d2e4-0004-11f0-ae42-38d57ae2c1c1": {
+		ID:           "b3c1d2e4-0004-11f0-ae42-38d57ae2c1c1",
+		Name:         "Literature Center DVFU",
+		Description:  "Литературный центр и редакция университетского издания",
+		Contacts:     OrganizationContacts{Email: "litcenter@dvfu.ru"},
+		Verification: VerificationVerified,
+	},
+	"b3c1d2e4-0005-11f0-ae42-38d57ae2c1c1": {
+		ID:           "b3c1d2e4-0005-11f0-ae42-38d57ae2c1c1",
+		Name:         "ICPC Training Center",
+		Description:  "Центр подготовки к олимпиадам по программированию",
+		Contacts:     OrganizationContacts{Email: "icpc@dvfu.ru"},
+		Verification: VerificationVerified,
+	},
+	"e1f2a3b4-c5d6-7e8f-9a0b-1c2d3e4f5a6b": {
+		ID:           "e1f2a3b4-c5d6-7e8f-9a0b-1c2d3e4f5a6b",
+		Name:         "Data Science Lab",
+		Description:  "Лаборатория анализа данных и машинного обучения",
+		Contacts:     OrganizationContacts{Email: "ds@dslab.ru", Website: "https://dslab.ru"},
+		Verification: VerificationVerified,
+		Commercial:   true,
+	},
+	"b3c1d2e4-0006-11f0-ae42-38d57ae2c1c1": {
+		ID:           "b3c1d2e4-0006-11f0-ae42-38d57ae2c1c1",
+		Name:         "Green Initiative DVFU",
+		Description:  "Студенческое экологическое движение",
+		Contacts:     OrganizationContacts{Email: "green@dvfu.ru"},
+		Verification: VerificationVerified,
+	},
+	"b3c1d2e4-0007-11f0-ae42-38d57ae2c1c1": {
+		ID:           "b3c1d2e4-0007-11f0-ae42-38d57ae2c1c1",
+		Name:         "Mobile Dev Studio",
+		Description:  "Разработка мобильных приложений под iOS и Android",
+		Contacts:     OrganizationContacts{Email: "hr@mobiledev.ru"},
+		Verification: VerificationPending,
+		Commercial:   true,
+	},
+	"b3c1d2e4-0008-11f0-ae42-38d57ae2c1c1": {
+		ID:           "b3c1d2e4-0008-11f0-ae42-38d57ae2c1c1",
+		Name:         "Medical Research Institute",
+		Description:  "Научно-исследовательский медицинский институт",
+		Contacts:     OrganizationContacts{Email: "info@mri.ru"},
+		Verification: VerificationVerified,
+	},
+	"b3c1d2e4-0009-11f0-ae42-38d57ae2c1c1": {
+		ID:           "b3c1d2e4-0009-11f0-ae42-38d57ae2c1c1",
+		Name:         "Vladivostok Library",
+		Description:  "Центральная городская библиотека",
+		Contacts:     OrganizationContacts{Phone: "+7 423 222-00-00", Address: "ул. Светланская, 43"},
+		Verification: VerificationVerified,
+	},
+	"b3c1d2e4-0010-11f0-ae42-38d57ae2c1c1": {
+		ID:           "b3c1d2e4-0010-11f0-ae42-38d57ae2c1c1",
+		Name:         "IoT Innovations",
+		Description:  "Разработка устройств интернета вещей",
+		Contacts:     OrganizationContacts{Email: "team@iot-innovations.ru"},
+		Verification: VerificationNone,
+		Commercial:   true,
+	},
+}
+
+// isOpenVacancy сообщает, принимает ли вакансия отклики на момент now.
+// DateOfEnd - полночь последнего дня, поэтому вакансия открыта весь этот день.
+func isOpenVacancy(v Vacancy, now time.Time) bool {
+	return now.Before(v.DateOfEnd.AddDate(0, 0, 1))
+}
+
+// organizationVacancies возвращает открытые вакансии организации
+func organizationVacancies(id string) []Vacancy {
+	now := time.Now()
+	result := make([]Vacancy, 0)
+	for _, v := range vacancies {
+		if v.OrganizationID == id && isOpenVacancy(v, now) {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// 19. Get Organization List - GET /JobService/hs/jobservice/organizationlist/?verified=true
+func getOrganizationList(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	verifiedOnly := r.URL.Query().Get("verified") == "true"
+
+	type organizationItem struct {
+		Organization
+		OpenVacancies int `json:"OpenVacancies"`
+	}
+
+	dataMu.RLock()
+	result := make([]organizationItem, 0, len(organizations))
+	for _, o := range organizations {
+		if verifiedOnly && o.Verification != VerificationVerified {
+			continue
+		}
+		result = append(result, organizationItem{Organization: o, OpenVacancies: len(organizationVacancies(o.ID))})
+	}
+	dataMu.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+
+	requestLogger(r).Debug("возвращены организации", "count", len(result))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// 20. Get Organization - GET /JobService/hs/jobservice/organization/?id=GUID
+// Вместе с карточкой организации возвращаются ее открытые вакансии.
+func getOrganization(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	id := r.URL.Query().Get("id")
+
+	dataMu.RLock()
+	o, ok := organizations[id]
+	var open []Vacancy
+	if ok {
+		open = organizationVacancies(id)
+	}
+	dataMu.RUnlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "организация не найдена")
+		return
+	}
+
+	requestLogger(r).Debug("организация найдена", "organization", o.ID)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		Organization
+		Vacancies []Vacancy `json:"Vacancies"`
+	}{o, open})
+}
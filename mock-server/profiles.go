@@ -0,0 +1,262 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// StudentProfile - анкета студента, видна работодателям в списке откликов
+type StudentProfile struct {
+	Student   string             `json:"Student"`
+	FullName  string             `json:"FullName"`
+	Institute string             `json:"Institute"`
+	Faculty   string             `json:"Faculty"`
+	Course    int                `json:"Course"`
+	Skills    []string           `json:"Skills"`
+	Bio       string             `json:"Bio"`
+	Contacts  ContactPreferences `json:"Contacts"`
+
+	// Пожелания к работе, учитываются в рекомендациях
+	DesiredSalary int       `json:"DesiredSalary"`
+	AvailableFrom time.Time `json:"AvailableFrom,omitzero"`
+	AvailableTo   time.Time `json:"AvailableTo,omitzero"`
+}
+
+// ContactPreferences - контакты студента и предпочтительный способ связи
+type ContactPreferences struct {
+	Email     string `json:"Email"`
+	Phone     string `json:"Phone"`
+	Telegram  string `json:"Telegram"`
+	Preferred string `json:"Preferred"`
+}
+
+// ProfileSummary - краткая анкета, встраивается в отклики /requestlist
+type ProfileSummary struct {
+	FullName  string   `json:"FullName"`
+	Institute string   `json:"Institute"`
+	Faculty   string   `json:"Faculty"`
+	Course    int      `json:"Course"`
+	Skills    []string `json:"Skills"`
+}
+
+// Допустимые значения ContactPreferences.Preferred
+var contactMethods = []string{"", "email", "phone", "telegram"}
+
+const maxBioLength = 1000
+
+var profiles = map[string]StudentProfile{
+	"123-694-775 67": {
+		Student:   "123-694-775 67",
+		FullName:  "Иванов Иван Иванович",
+		Institute: "Институт математики и компьютерных технологий",
+		Faculty:   "Прикладная математика и информатика",
+		Course:    3,
+		Skills:    []string{"Программирование", "C++", "Алгоритмы", "Обучение"},
+		Bio:       "Призер региональной олимпиады по программированию, три года помогаю первокурсникам с C++.",
+		Contacts: ContactPreferences{
+			Email:     "ivanov.ii@students.dvfu.ru",
+			Telegram:  "@ivanov_ii",
+			Preferred: "telegram",
+		},
+	},
+	"234-567-890 12": {
+		Student:   "234-567-890 12",
+		FullName:  "Смирнова Дарья Павловна",
+		Institute: "Школа искусств и гуманитарных наук",
+		Faculty:   "Журналистика",
+		Course:    2,
+		Skills:    []string{"Литература", "Редакция", "Творчество"},
+		Bio:       "Пишу для студенческой газеты, интересуюсь редактурой и издательским делом.",
+		Contacts: ContactPreferences{
+			Email:     "smirnova.dp@students.dvfu.ru",
+			Preferred: "email",
+		},
+	},
+	"345-678-901 23": {
+		Student:   "345-678-901 23",
+		FullName:  "Волков Илья Андреевич",
+		Institute: "Институт математики и компьютерных технологий",
+		Faculty:   "Программная инженерия",
+		Course:    4,
+		Skills:    []string{"Программирование", "Go", "Backend", "API"},
+		Bio:       "Пишу на Go, знаком с PostgreSQL и Docker.",
+		Contacts: ContactPreferences{
+			Email:     "volkov.ia@students.dvfu.ru",
+			Phone:     "+7 900 000-00-01",
+			Preferred: "phone",
+		},
+		DesiredSalary: 100000,
+		AvailableFrom: parseDate("20261101"),
+		AvailableTo:   parseDate("20270531"),
+	},
+	"456-789-012 34": {
+		Student:   "456-789-012 34",
+		FullName:  "Лебедева Виктория Сергеевна",
+		Institute: "Школа медицины и наук о жизни",
+		Faculty:   "Лечебное дело",
+		Course:    1,
+		Skills:    []string{"Медицина", "Помощь пожилым", "Общественная польза"},
+		Contacts: ContactPreferences{
+			Email:     "lebedeva.vs@students.dvfu.ru",
+			Preferred: "email",
+		},
+	},
+}
+
+// isStudent проверяет, что идентификатор принадлежит аккаунту студента
+func isStudent(student string) bool {
+	if student == "" {
+		return false
+	}
+	_, _, ok := findAccount(func(a Account) bool { return a.Student == student })
+	return ok
+}
+
+// profileSummary возвращает краткую анкету студента или nil, если анкеты нет
+func profileSummary(student string) *ProfileSummary {
+	p, ok := profiles[student]
+	if !ok {
+		return nil
+	}
+	return &ProfileSummary{
+		FullName:  p.FullName,
+		Institute: p.Institute,
+		Faculty:   p.Faculty,
+		Course:    p.Course,
+		Skills:    p.Skills,
+	}
+}
+
+// studentName возвращает ФИО студента из анкеты, а при ее отсутствии - идентификатор
+func studentName(student string) string {
+	if p, ok := profiles[student]; ok && p.FullName != "" {
+		return p.FullName
+	}
+	return student
+}
+
+// validateProfile проверяет поля анкеты перед сохранением и приводит навыки к названиям из справочника.
+// Вызывающий держит dataMu.
+func validateProfile(p *StudentProfile) error {
+	if strings.TrimSpace(p.FullName) == "" {
+		return fmt.Errorf("не указано ФИО")
+	}
+	if p.Course < 1 || p.Course > 6 {
+		return fmt.Errorf("курс должен быть от 1 до 6")
+	}
+	if len([]rune(p.Bio)) > maxBioLength {
+		return fmt.Errorf("описание не должно превышать %d символов", maxBioLength)
+	}
+	skills, err := canonicalTags(p.Skills)
+	if err != nil {
+		return err
+	}
+	p.Skills = skills
+	if p.DesiredSalary < 0 {
+		return fmt.Errorf("желаемая зарплата не может быть отрицательной")
+	}
+	if !p.AvailableFrom.IsZero() && !p.AvailableTo.IsZero() && p.AvailableFrom.After(p.AvailableTo) {
+		return fmt.Errorf("дата начала доступности не может быть позже даты окончания")
+	}
+	if !slices.Contains(contactMethods, p.Contacts.Preferred) {
+		return fmt.Errorf("неизвестный способ связи: %s", p.Contacts.Preferred)
+	}
+	return nil
+}
+
+// appliedTo проверяет, что студент откликался на вакансию организации.
+// Вызывающий держит dataMu.
+func appliedTo(student, organization string) bool {
+	for _, req := range requests {
+		if req.Student != student {
+			continue
+		}
+		if v, ok := requestVacancy(req); ok && v.OrganizationID == organization {
+			return true
+		}
+	}
+	return false
+}
+
+// 17. Get Profile - GET /JobService/hs/jobservice/profile/?student=Student&organization=GUID
+// Контакты видят только сам студент и организации, на вакансии которых он откликался.
+// Кто спрашивает, определяется как в requestStudent: по сессии, а без нее,
+// пока вход через поставщика не настроен, по параметрам - запрос без
+// organization считается запросом самого студента.
+func getProfile(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	q := r.URL.Query()
+	student := q.Get("student")
+	own, organization := false, q.Get("organization")
+	if s, ok := requestSession(r); ok {
+		own, organization = s.Account.Student == student, s.Account.Organization
+	} else if sso != nil {
+		organization = ""
+	} else {
+		own = organization == ""
+	}
+
+	dataMu.RLock()
+	p, ok := profiles[student]
+	contacts := own || organization != "" && appliedTo(student, organization)
+	dataMu.RUnlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "анкета не найдена")
+		return
+	}
+	if !contacts {
+		p.Contacts = ContactPreferences{}
+	}
+
+	requestLogger(r).Debug("анкета найдена")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(p)
+}
+
+// 18. Save Profile - POST /JobService/hs/jobservice/profile/?student=Student
+// Студент может изменять только свою анкету: идентификатор в запросе и в теле должны совпадать.
+func saveProfile(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	student, ok := requestStudent(w, r, r.URL.Query().Get("student"))
+	if !ok {
+		return
+	}
+
+	var p StudentProfile
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		writeError(w, http.StatusBadRequest, "некорректный JSON")
+		return
+	}
+	if p.Student == "" {
+		p.Student = student
+	}
+	if !isStudent(student) || p.Student != student {
+		writeError(w, http.StatusForbidden, "можно изменять только свою анкету")
+		return
+	}
+
+	dataMu.Lock()
+	defer dataMu.Unlock()
+
+	if err := validateProfile(&p); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var before any
+	if old, ok := profiles[student]; ok {
+		before = old
+	}
+	profiles[student] = p
+	audit(r, accountLogin("", student), AuditProfileSave, student, before, p)
+
+	requestLogger(r).Info("анкета сохранена")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(p)
+}
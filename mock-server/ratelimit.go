@@ -0,0 +1,286 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"shared"
+)
+
+// Частота запросов ограничивается «корзиной токенов» отдельно для адреса
+// клиента и для пользователя с сессией: запрос проходит, только если токен
+// есть в обеих.
+// Лимиты задаются по имени маршрута (последний сегмент пути: checkaccount,
+// request, faq); "write" действует на остальные POST-запросы.
+
+// RateLimit - не больше Requests запросов за Per, с возможностью потратить их разом
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+}
+
+func (l RateLimit) String() string {
+	return fmt.Sprintf("%d/%s", l.Requests, l.Per)
+}
+
+// rateLimitWrite - имя лимита для POST-запросов без собственного лимита
+const rateLimitWrite = "write"
+
+const defaultRateLimits = "checkaccount=10/1m,request=5/1m,faq=3/1m,write=60/1m"
+
+var rateLimits = mustParseRateLimits(defaultRateLimits)
+
+// parseRateLimits разбирает лимиты вида "checkaccount=10/1m,faq=3/1m".
+// Лимит 0 снимает ограничение с маршрута.
+func parseRateLimits(s string) (map[string]RateLimit, error) {
+	limits := map[string]RateLimit{}
+	for item := range strings.SplitSeq(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(item, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("ожидается маршрут=количество/период, получено %q", item)
+		}
+		name = strings.TrimSpace(name)
+		if value == "0" {
+			delete(limits, name)
+			continue
+		}
+		count, period, ok := strings.Cut(value, "/")
+		n, err := strconv.Atoi(count)
+		if !ok || err != nil || n <= 0 {
+			return nil, fmt.Errorf("%s: некорректное количество запросов %q", name, value)
+		}
+		per, err := time.ParseDuration(period)
+		if err != nil || per <= 0 {
+			return nil, fmt.Errorf("%s: некорректный период %q", name, period)
+		}
+		limits[name] = RateLimit{Requests: n, Per: per}
+	}
+	return limits, nil
+}
+
+func mustParseRateLimits(s string) map[string]RateLimit {
+	limits, err := parseRateLimits(s)
+	if err != nil {
+		panic(err)
+	}
+	return limits
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	limit  RateLimit
+}
+
+// refill пополняет корзину за время с последнего обращения
+func (b *tokenBucket) refill(now time.Time) {
+	rate := float64(b.limit.Requests) / b.limit.Per.Seconds()
+	b.tokens = math.Min(float64(b.limit.Requests), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+}
+
+// wait возвращает, через сколько в корзине появится токен
+func (b *tokenBucket) wait() time.Duration {
+	rate := float64(b.limit.Requests) / b.limit.Per.Seconds()
+	return time.Duration((1 - b.tokens) / rate * float64(time.Second))
+}
+
+var limiter struct {
+	sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// takeTokens берет по токену из каждой корзины, если во всех они есть.
+// Иначе ничего не списывает и возвращает время до появления токенов.
+func takeTokens(keys []string, limit RateLimit, now time.Time) (bool, time.Duration) {
+	limiter.Lock()
+	defer limiter.Unlock()
+
+	if limiter.buckets == nil {
+		limiter.buckets = map[string]*tokenBucket{}
+	}
+	sweepBuckets(now)
+
+	var wait time.Duration
+	buckets := make([]*tokenBucket, 0, len(keys))
+	for _, key := range keys {
+		b, ok := limiter.buckets[key]
+		if !ok || b.limit != limit {
+			b = &tokenBucket{tokens: float64(limit.Requests), last: now, limit: limit}
+			limiter.buckets[key] = b
+		}
+		b.refill(now)
+		if b.tokens < 1 {
+			wait = max(wait, b.wait())
+		}
+		buckets = append(buckets, b)
+	}
+	if wait > 0 {
+		return false, wait
+	}
+	for _, b := range buckets {
+		b.tokens--
+	}
+	return true, 0
+}
+
+// sweepBuckets раз в минуту удаляет корзины, которые успели заполниться:
+// они ничем не отличаются от новых. Вызывающий держит limiter.
+func sweepBuckets(now time.Time) {
+	if now.Sub(limiter.lastSweep) < time.Minute {
+		return
+	}
+	limiter.lastSweep = now
+	for key, b := range limiter.buckets {
+		if now.Sub(b.last) >= b.limit.Per {
+			delete(limiter.buckets, key)
+		}
+	}
+}
+
+// routeName возвращает имя маршрута для лимитов: последний сегмент шаблона
+func routeName(pattern string) string {
+	if _, p, ok := strings.Cut(pattern, " "); ok {
+		pattern = p
+	}
+	return path.Base(strings.TrimSuffix(pattern, "/"))
+}
+
+// requestAccount определяет пользователя по сессии. Параметрам запроса и
+// полям тела здесь не верят: подставив чужой логин, можно было бы исчерпать
+// лимит другого пользователя. Без сессии действует только лимит адреса.
+func requestAccount(r *http.Request) string {
+	if s, ok := requestSession(r); ok {
+		return "user:" + s.Login
+	}
+	return ""
+}
+
+// tooManyRequests отвечает 429 с заголовком Retry-After в секундах
+func tooManyRequests(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	writeError(w, http.StatusTooManyRequests, "слишком много запросов, повторите позже")
+}
+
+// rateLimitMiddleware ограничивает частоту запросов по лимитам rateLimits
+func rateLimitMiddleware(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := mux.Handler(r)
+		name := routeName(pattern)
+		limit, ok := rateLimits[name]
+		if !ok && r.Method == http.MethodPost {
+			limit, ok = rateLimits[rateLimitWrite]
+		}
+		if pattern == "" || !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ip := shared.ClientIP(r)
+		keys := []string{name + "|ip|" + ip}
+		if account := requestAccount(r); account != "" {
+			keys = append(keys, name+"|"+account)
+		}
+		if allowed, wait := takeTokens(keys, limit, time.Now()); !allowed {
+			requestLogger(r).Warn("превышен лимит запросов", "route", name, "limit", limit.String(), "ip", ip)
+			tooManyRequests(w, wait)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Блокировка входа: после loginMaxFailures неудачных попыток подряд вход под
+// логином с адреса клиента блокируется на loginLockout. Блокируется пара
+// логин и адрес, чтобы чужие попытки не закрывали вход владельцу логина,
+// а соседи по NAT не мешали друг другу. Каждая следующая блокировка вдвое
+// дольше предыдущей, но не дольше loginMaxLockout.
+const (
+	loginMaxFailures = 5
+	loginLockout     = time.Minute
+	loginMaxLockout  = time.Hour
+	// loginForgetAfter - через сколько без неудачных попыток счетчики сбрасываются
+	loginForgetAfter = 24 * time.Hour
+)
+
+type loginState struct {
+	failures    int
+	lockouts    int
+	lockedUntil time.Time
+	lastFailure time.Time
+}
+
+var loginGuard struct {
+	sync.Mutex
+	states map[string]*loginState
+}
+
+// loginKey возвращает ключ блокировки для логина и адреса клиента
+func loginKey(r *http.Request, login string) string {
+	return login + "|" + shared.ClientIP(r)
+}
+
+// loginLockedFor возвращает, сколько еще продлится блокировка входа
+func loginLockedFor(key string, now time.Time) time.Duration {
+	loginGuard.Lock()
+	defer loginGuard.Unlock()
+
+	if s, ok := loginGuard.states[key]; ok {
+		return max(0, s.lockedUntil.Sub(now))
+	}
+	return 0
+}
+
+// loginFailed учитывает неудачную попытку входа
+func loginFailed(key string, now time.Time) {
+	loginGuard.Lock()
+	defer loginGuard.Unlock()
+
+	if loginGuard.states == nil {
+		loginGuard.states = map[string]*loginState{}
+	}
+	for key, s := range loginGuard.states {
+		if now.Sub(s.lastFailure) > loginForgetAfter {
+			delete(loginGuard.states, key)
+		}
+	}
+
+	s, ok := loginGuard.states[key]
+	if !ok {
+		s = &loginState{}
+		loginGuard.states[key] = s
+	}
+	s.failures++
+	s.lastFailure = now
+	if s.failures < loginMaxFailures {
+		return
+	}
+	lockout := loginMaxLockout
+	if s.lockouts < 8 {
+		lockout = min(loginLockout<<s.lockouts, loginMaxLockout)
+	}
+	s.failures = 0
+	s.lockouts++
+	s.lockedUntil = now.Add(lockout)
+	// Логин в журнал не пишется, как и в параметрах запроса
+	slog.Warn("вход заблокирован после неудачных попыток", "lockout", lockout.String())
+}
+
+// loginSucceeded сбрасывает счетчик неудачных попыток для логина с этого адреса
+func loginSucceeded(key string) {
+	loginGuard.Lock()
+	defer loginGuard.Unlock()
+	delete(loginGuard.states, key)
+}
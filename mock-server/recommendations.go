@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Вклад каждого признака в итоговую оценку рекомендации
+const (
+	recommendSkillsWeight  = 0.5
+	recommendHistoryWeight = 0.2
+	recommendSalaryWeight  = 0.15
+	recommendDatesWeight   = 0.15
+
+	defaultRecommendationLimit = 10
+)
+
+// Recommendation - вакансия, рекомендованная студенту, с объяснением
+type Recommendation struct {
+	Vacancy Vacancy  `json:"Vacancy"`
+	Score   float64  `json:"Score"`
+	Reasons []string `json:"Reasons"`
+}
+
+// studentHistory возвращает направления работ вакансий, на которые откликался студент,
+// и номера этих вакансий
+func studentHistory(student string) (map[string]bool, map[string]bool) {
+	tagsSeen := map[string]bool{}
+	applied := map[string]bool{}
+	for _, req := range requests {
+		if req.Student != student {
+			continue
+		}
+		applied[req.Number] = true
+		v, ok := findVacancy(req.Number)
+		if !ok {
+			v, ok = closedVacancies[req.Number]
+		}
+		if ok {
+			for _, t := range v.TypesOfWork {
+				tagsSeen[t] = true
+			}
+		}
+	}
+	return tagsSeen, applied
+}
+
+// overlap возвращает направления вакансии из набора set
+func overlap(typesOfWork []string, set map[string]bool) []string {
+	var result []string
+	for _, t := range typesOfWork {
+		if set[t] {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// recommend оценивает открытые вакансии для студента. Вакансии, на которые
+// студент уже откликался, и вакансии без единого совпадения не возвращаются.
+func recommend(student string, now time.Time) []Recommendation {
+	profile := profiles[student]
+	skills := map[string]bool{}
+	for _, s := range profile.Skills {
+		skills[s] = true
+	}
+	history, applied := studentHistory(student)
+
+	var result []Recommendation
+	for _, v := range vacancies {
+		if applied[v.Number] || !isOpenVacancy(v, now) || len(v.TypesOfWork) == 0 {
+			continue
+		}
+
+		var score float64
+		var reasons []string
+		n := float64(len(v.TypesOfWork))
+
+		if common := overlap(v.TypesOfWork, skills); len(common) > 0 {
+			score += recommendSkillsWeight * float64(len(common)) / n
+			reasons = append(reasons, "Совпадают ваши навыки: "+strings.Join(common, ", "))
+		}
+		if common := overlap(v.TypesOfWork, history); len(common) > 0 {
+			score += recommendHistoryWeight * float64(len(common)) / n
+			reasons = append(reasons, "Похоже на вакансии, на которые вы откликались: "+strings.Join(common, ", "))
+		}
+		if score == 0 {
+			continue
+		}
+
+		if profile.DesiredSalary > 0 {
+			if v.Salary >= profile.DesiredSalary {
+				score += recommendSalaryWeight
+				reasons = append(reasons, fmt.Sprintf("Зарплата %d ₽ не ниже желаемой", v.Salary))
+			} else {
+				score += recommendSalaryWeight * float64(v.Salary) / float64(profile.DesiredSalary)
+			}
+		}
+
+		if share := availabilityShare(v, profile); share > 0 {
+			score += recommendDatesWeight * share
+			if share == 1 {
+				reasons = append(reasons, "Сроки работы полностью совпадают с вашей доступностью")
+			} else {
+				reasons = append(reasons, fmt.Sprintf("Сроки работы совпадают с вашей доступностью на %d%%", int(share*100)))
+			}
+		}
+
+		result = append(result, Recommendation{Vacancy: v, Score: score, Reasons: reasons})
+	}
+
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Score > result[j].Score })
+	return result
+}
+
+// availabilityShare возвращает долю периода вакансии, когда студент свободен.
+// Если доступность в анкете не указана, возвращается 0.
+func availabilityShare(v Vacancy, p StudentProfile) float64 {
+	if p.AvailableFrom.IsZero() && p.AvailableTo.IsZero() {
+		return 0
+	}
+	from, to := v.DateOfBegin, v.DateOfEnd
+	if !p.AvailableFrom.IsZero() && p.AvailableFrom.After(from) {
+		from = p.AvailableFrom
+	}
+	if !p.AvailableTo.IsZero() && p.AvailableTo.Before(to) {
+		to = p.AvailableTo
+	}
+	total := v.DateOfEnd.Sub(v.DateOfBegin)
+	if total <= 0 || !to.After(from) {
+		return 0
+	}
+	return min(1, float64(to.Sub(from))/float64(total))
+}
+
+// 29. Get Recommendations - GET /JobService/hs/jobservice/recommendations/?student=Student&limit=10
+func getRecommendations(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	student, ok := requestStudent(w, r, r.URL.Query().Get("student"))
+	if !ok {
+		return
+	}
+	if !isStudent(student) {
+		writeError(w, http.StatusForbidden, "рекомендации доступны только студентам")
+		return
+	}
+	limit := defaultRecommendationLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "некорректный limit")
+			return
+		}
+		limit = n
+	}
+
+	dataMu.RLock()
+	result := recommend(student, time.Now())
+	dataMu.RUnlock()
+
+	result = result[:min(limit, len(result))]
+	if result == nil {
+		result = []Recommendation{}
+	}
+
+	requestLogger(r).Debug("возвращены рекомендации", "count", len(result))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+)
+
+// SavedSearch - сохраненный студентом набор фильтров /vacancylist.
+// При публикации новой подходящей вакансии студент получает уведомление,
+// а при Email = true - еще и письмо на адрес из анкеты.
+type SavedSearch struct {
+	ID      string            `json:"ID"`
+	Student string            `json:"Student"`
+	Name    string            `json:"Name"`
+	Params  map[string]string `json:"Params"`
+	Email   bool              `json:"Email"`
+	Created time.Time         `json:"Created"`
+}
+
+// Mailer отправляет письма студентам
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// logMailer только печатает письмо: почтового сервера у мок-сервера нет
+type logMailer struct{}
+
+func (logMailer) Send(to, subject, body string) error {
+	slog.Info("письмо отправлено", "subject", subject)
+	slog.Debug("текст письма", "body", body)
+	return nil
+}
+
+const maxSavedSearchesPerStudent = 20
+
+var mailer Mailer = logMailer{}
+
+var (
+	savedSearches     = []SavedSearch{}
+	lastSavedSearchID = 0
+)
+
+// publishQueueSize - сколько опубликованных вакансий может ждать обработчика подписок
+const publishQueueSize = 256
+
+// publishing передает номера опубликованных вакансий фоновому обработчику
+// подписок. Очередь ограничена: если рассылка писем отстает и очередь
+// заполнена, вакансия не рассылается, а в журнал пишется предупреждение.
+// При остановке сервера очередь закрывается под мьютексом, и новые вакансии
+// не принимаются: запрос, прерванный по таймауту остановки, может
+// дорабатывать и после нее.
+var publishing = struct {
+	sync.Mutex
+	queue  chan string
+	closed bool
+}{queue: make(chan string, publishQueueSize)}
+
+// closePublishing закрывает очередь подписок при остановке сервера.
+// Обработчик разбирает оставшиеся вакансии и завершается.
+func closePublishing() {
+	publishing.Lock()
+	defer publishing.Unlock()
+	if !publishing.closed {
+		publishing.closed = true
+		close(publishing.queue)
+	}
+}
+
+// filter разбирает сохраненные параметры так же, как /vacancylist
+func (s SavedSearch) filter() (VacancyFilter, error) {
+	q := url.Values{}
+	for k, v := range s.Params {
+		q.Set(k, v)
+	}
+	return parseVacancyFilter(q)
+}
+
+// publishVacancy вызывается, когда вакансия становится видна студентам.
+// Вызывающий держит dataMu.
+func publishVacancy(v Vacancy) {
+	vacancyIndex.Add(v)
+	invalidateTagStats()
+
+	publishing.Lock()
+	defer publishing.Unlock()
+	if publishing.closed {
+		slog.Warn("сервер остановлен, вакансия не разослана по подпискам", "vacancy", v.Number)
+		return
+	}
+	select {
+	case publishing.queue <- v.Number:
+	default:
+		slog.Warn("очередь подписок заполнена, вакансия не разослана по подпискам", "vacancy", v.Number)
+	}
+}
+
+// runSavedSearchMatcher сопоставляет опубликованные вакансии с подписками
+// студентов, пока очередь не закроют и не разберут до конца
+func runSavedSearchMatcher() {
+	for number := range publishing.queue {
+		matchSavedSearches(number)
+	}
+}
+
+// matchSavedSearches рассылает уведомления о вакансии по подходящим подпискам.
+// Пока вакансия ждала в очереди, ее могли закрыть или снять с публикации,
+// поэтому она заново ищется среди открытых.
+func matchSavedSearches(number string) {
+	type mail struct{ to, subject, body string }
+	var mails []mail
+
+	dataMu.Lock()
+	v, ok := findVacancy(number)
+	if !ok || !isOpenVacancy(v, time.Now()) {
+		dataMu.Unlock()
+		slog.Debug("вакансия закрыта до рассылки по подпискам", "vacancy", number)
+		return
+	}
+	notified := map[string]bool{}
+	for _, s := range savedSearches {
+		if notified[s.Student] {
+			continue
+		}
+		f, err := s.filter()
+		if err != nil || len(f.Apply([]Vacancy{v})) == 0 {
+			continue
+		}
+		notified[s.Student] = true
+
+		text := fmt.Sprintf("Новая вакансия по подписке «%s»: %s (%s)", s.Name, v.Title, v.Organization)
+		addNotify(Notify{
+			Text:            text,
+			Date:            time.Now(),
+			NumberOfRequest: v.Number,
+			Student:         s.Student,
+		})
+		if p, ok := profiles[s.Student]; s.Email && ok && p.Contacts.Email != "" {
+			mails = append(mails, mail{p.Contacts.Email, "Новая вакансия: " + v.Title, text})
+		}
+	}
+	dataMu.Unlock()
+
+	for _, m := range mails {
+		if err := mailer.Send(m.to, m.subject, m.body); err != nil {
+			slog.Error("письмо не отправлено", "error", err)
+		}
+	}
+	if len(notified) > 0 {
+		slog.Info("отправлены уведомления по подпискам", "vacancy", v.Number, "count", len(notified))
+	}
+}
+
+// 23. Get Saved Searches - GET /JobService/hs/jobservice/savedsearches/?student=Student
+func getSavedSearches(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	student, ok := requestStudent(w, r, r.URL.Query().Get("student"))
+	if !ok {
+		return
+	}
+
+	dataMu.RLock()
+	result := make([]SavedSearch, 0)
+	for _, s := range savedSearches {
+		if s.Student == student {
+			result = append(result, s)
+		}
+	}
+	dataMu.RUnlock()
+
+	requestLogger(r).Debug("возвращены подписки", "count", len(result))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// 24. Create Saved Search - POST /JobService/hs/jobservice/savedsearch
+func createSavedSearch(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	var data struct {
+		Student string            `json:"student"`
+		Name    string            `json:"name"`
+		Params  map[string]string `json:"params"`
+		Email   bool              `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		writeError(w, http.StatusBadRequest, "некорректный JSON")
+		return
+	}
+	student, ok := requestStudent(w, r, data.Student)
+	if !ok {
+		return
+	}
+	data.Student = student
+	if !isStudent(data.Student) {
+		writeError(w, http.StatusForbidden, "подписки доступны только студентам")
+		return
+	}
+	if data.Name == "" {
+		writeError(w, http.StatusBadRequest, "не указано название подписки")
+		return
+	}
+	for k := range data.Params {
+		if !slices.Contains(vacancyFilterParams, k) {
+			writeError(w, http.StatusBadRequest, "неизвестный параметр фильтра: "+k)
+			return
+		}
+	}
+
+	s := SavedSearch{
+		Student: data.Student,
+		Name:    data.Name,
+		Params:  data.Params,
+		Email:   data.Email,
+		Created: time.Now(),
+	}
+	if _, err := s.filter(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	dataMu.Lock()
+	defer dataMu.Unlock()
+
+	count := 0
+	for _, existing := range savedSearches {
+		if existing.Student == data.Student {
+			count++
+		}
+	}
+	if count >= maxSavedSearchesPerStudent {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("можно сохранить не более %d подписок", maxSavedSearchesPerStudent))
+		return
+	}
+
+	lastSavedSearchID++
+	s.ID = formatNumber(lastSavedSearchID)
+	savedSearches = append(savedSearches, s)
+	audit(r, accountLogin("", s.Student), AuditSavedSearchCreate, s.ID, nil, s)
+
+	requestLogger(r).Info("подписка сохранена", "savedsearch", s.ID)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(s)
+}
+
+// 25. Delete Saved Search - POST /JobService/hs/jobservice/deletesavedsearch/?id=ID&student=Student
+func deleteSavedSearch(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	id := r.URL.Query().Get("id")
+	student, ok := requestStudent(w, r, r.URL.Query().Get("student"))
+	if !ok {
+		return
+	}
+
+	dataMu.Lock()
+	defer dataMu.Unlock()
+
+	i := slices.IndexFunc(savedSearches, func(s SavedSearch) bool {
+		return s.ID == id && s.Student == student
+	})
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "подписка не найдена")
+		return
+	}
+	before := savedSearches[i]
+	savedSearches = slices.Delete(savedSearches, i, i+1)
+	audit(r, accountLogin("", student), AuditSavedSearchDelete, id, before, nil)
+
+	requestLogger(r).Info("подписка удалена", "savedsearch", id)
+	w.WriteHeader(http.StatusOK)
+}
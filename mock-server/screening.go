@@ -0,0 +1,303 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Тексты вакансий и откликов перед публикацией проходят автоматическую проверку.
+// Найденные нарушения не отклоняют запрос, а отправляют его модератору.
+
+// Виды проверяемых текстов
+const (
+	ScreeningVacancy = "vacancy"
+	ScreeningRequest = "request"
+)
+
+// ScreeningSubject - проверяемая вакансия или отклик
+type ScreeningSubject struct {
+	Kind         string
+	Texts        []string
+	Salary       int
+	Organization Organization
+}
+
+// ScreeningFinding - нарушение, найденное правилом проверки
+type ScreeningFinding struct {
+	Rule    string `json:"Rule"`
+	Message string `json:"Message"`
+}
+
+// ScreeningRule - одно правило проверки
+type ScreeningRule interface {
+	Name() string
+	Check(s ScreeningSubject) []string
+}
+
+// ScreeningConfig - настройки правил проверки. Читаются из screening.json
+// при запуске и меняются администратором через /screeningrules.
+type ScreeningConfig struct {
+	// BannedWords - запрещенные слова и корни нецензурной лексики
+	BannedWords []string `json:"BannedWords"`
+	// ScamPatterns - регулярные выражения, типичные для мошеннических объявлений
+	ScamPatterns []string `json:"ScamPatterns"`
+	// AllowPhones и AllowLinks разрешают телефоны и внешние ссылки в текстах вакансий
+	AllowPhones bool `json:"AllowPhones"`
+	AllowLinks  bool `json:"AllowLinks"`
+	// MaxSalary - зарплата, выше которой вакансия считается подозрительной
+	MaxSalary int `json:"MaxSalary"`
+}
+
+const screeningConfigFile = "screening.json"
+
+var defaultScreeningConfig = ScreeningConfig{
+	BannedWords: []string{
+		"бля", "хуй", "хуе", "хуё", "пизд", "еба", "ёба", "сука", "мудак", "гандон",
+		"казино", "закладк", "наркот",
+	},
+	ScamPatterns: []string{
+		`предоплат`,
+		`оплат\S* (обучени|регистраци|материал|стажировк)`,
+		`пассивн\S* доход`,
+		`криптовалют`,
+		`без вложени`,
+		`быстр\S* (заработ|деньг)`,
+		`данные (банковской )?карты`,
+		`(whatsapp|ватсап|вотсап|viber|вайбер)`,
+	},
+	MaxSalary: 500000,
+}
+
+var (
+	screeningConfig = defaultScreeningConfig
+	screeningRules  = mustBuildScreeningRules(defaultScreeningConfig)
+)
+
+var (
+	phonePattern = regexp.MustCompile(`(\+7|\b8)[\s\-(]*\d{3}[\s\-)]*\d{3}[\s\-]*\d{2}[\s\-]*\d{2}\b`)
+	// \b в RE2 знает только латиницу, поэтому границы доменного имени заданы
+	// классами Unicode: иначе «сайт.рф» не находился бы. Домен первого уровня
+	// сравнивается с учетом регистра, чтобы не принимать «ASP.NET» за сайт.
+	linkPattern = regexp.MustCompile(`(?i)(https?://|www\.|t\.me/)[^\s,;)]+` +
+		`|(?:^|[^\p{L}\p{N}\-.])([\p{L}\p{N}][\p{L}\p{N}\-]*\.(?-i:ru|com|net|org|io|me|рф)(?:/[^\s,;)]*)?)(?:$|[^\p{L}\p{N}])` +
+		`|@[a-z0-9_]{5,}`)
+)
+
+// findLink возвращает первую ссылку в тексте без соседних символов,
+// захваченных границами доменного имени
+func findLink(text string) string {
+	m := linkPattern.FindStringSubmatch(text)
+	switch {
+	case m == nil:
+		return ""
+	case m[2] != "":
+		return m[2]
+	}
+	return m[0]
+}
+
+// bannedWordsRule ищет слова, начинающиеся с запрещенного корня.
+// Кириллица и латиница сравниваются без различий, как в названиях тегов.
+type bannedWordsRule struct {
+	roots []string
+}
+
+func (bannedWordsRule) Name() string { return "banned_words" }
+
+func (r bannedWordsRule) Check(s ScreeningSubject) []string {
+	var found []string
+	for _, text := range s.Texts {
+		words := strings.FieldsFunc(text, func(c rune) bool { return !unicode.IsLetter(c) })
+		for _, word := range words {
+			key := tagKey(word)
+			for _, root := range r.roots {
+				if strings.HasPrefix(key, root) {
+					found = append(found, "недопустимое слово: "+word)
+					break
+				}
+			}
+		}
+	}
+	return found
+}
+
+// contactsRule запрещает телефоны и внешние ссылки в текстах вакансий:
+// студенты должны связываться с работодателем через отклик.
+type contactsRule struct {
+	allowPhones, allowLinks bool
+}
+
+func (contactsRule) Name() string { return "contacts" }
+
+func (r contactsRule) Check(s ScreeningSubject) []string {
+	if s.Kind != ScreeningVacancy {
+		return nil
+	}
+	var found []string
+	for _, text := range s.Texts {
+		if !r.allowPhones {
+			if m := phonePattern.FindString(text); m != "" {
+				found = append(found, "номер телефона в тексте: "+m)
+			}
+		}
+		if !r.allowLinks {
+			if m := findLink(text); m != "" {
+				found = append(found, "внешняя ссылка в тексте: "+m)
+			}
+		}
+	}
+	return found
+}
+
+// salaryRule ищет аномальную зарплату в вакансиях
+type salaryRule struct {
+	max int
+}
+
+func (salaryRule) Name() string { return "salary" }
+
+func (r salaryRule) Check(s ScreeningSubject) []string {
+	if s.Kind != ScreeningVacancy {
+		return nil
+	}
+	switch {
+	case s.Salary < 0:
+		return []string{"отрицательная зарплата"}
+	case s.Salary == 0 && s.Organization.Commercial:
+		return []string{"нулевая зарплата у коммерческой организации"}
+	case r.max > 0 && s.Salary > r.max:
+		return []string{fmt.Sprintf("зарплата %d ₽ превышает %d ₽", s.Salary, r.max)}
+	}
+	return nil
+}
+
+// scamRule ищет фразы, типичные для мошеннических объявлений
+type scamRule struct {
+	patterns []*regexp.Regexp
+}
+
+func (scamRule) Name() string { return "scam" }
+
+func (r scamRule) Check(s ScreeningSubject) []string {
+	var found []string
+	for _, text := range s.Texts {
+		for _, p := range r.patterns {
+			if m := p.FindString(text); m != "" {
+				found = append(found, "подозрительная фраза: "+m)
+			}
+		}
+	}
+	return found
+}
+
+// buildScreeningRules собирает правила проверки по настройкам
+func buildScreeningRules(cfg ScreeningConfig) ([]ScreeningRule, error) {
+	roots := make([]string, 0, len(cfg.BannedWords))
+	for _, w := range cfg.BannedWords {
+		if w = tagKey(w); w != "" {
+			roots = append(roots, w)
+		}
+	}
+	patterns := make([]*regexp.Regexp, 0, len(cfg.ScamPatterns))
+	for _, p := range cfg.ScamPatterns {
+		re, err := regexp.Compile("(?i)" + p)
+		if err != nil {
+			return nil, fmt.Errorf("некорректное выражение %q: %v", p, err)
+		}
+		patterns = append(patterns, re)
+	}
+	if cfg.MaxSalary < 0 {
+		return nil, fmt.Errorf("MaxSalary не может быть отрицательной")
+	}
+	return []ScreeningRule{
+		bannedWordsRule{roots: roots},
+		contactsRule{allowPhones: cfg.AllowPhones, allowLinks: cfg.AllowLinks},
+		salaryRule{max: cfg.MaxSalary},
+		scamRule{patterns: patterns},
+	}, nil
+}
+
+func mustBuildScreeningRules(cfg ScreeningConfig) []ScreeningRule {
+	rules, err := buildScreeningRules(cfg)
+	if err != nil {
+		panic(err)
+	}
+	return rules
+}
+
+// loadScreeningConfig читает настройки проверки из файла. Если файла нет,
+// остаются настройки по умолчанию.
+func loadScreeningConfig(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var cfg ScreeningConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	rules, err := buildScreeningRules(cfg)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	screeningConfig, screeningRules = cfg, rules
+	slog.Info("правила проверки текстов загружены", "path", path)
+	return nil
+}
+
+// screen прогоняет текст через все правила проверки. Вызывающий держит dataMu.
+func screen(s ScreeningSubject) []ScreeningFinding {
+	var findings []ScreeningFinding
+	for _, rule := range screeningRules {
+		for _, message := range rule.Check(s) {
+			findings = append(findings, ScreeningFinding{Rule: rule.Name(), Message: message})
+		}
+	}
+	if len(findings) > 0 {
+		slog.Warn("проверка текста нашла нарушения", "kind", s.Kind, "findings", len(findings))
+	}
+	return findings
+}
+
+// 49. Screening Rules - GET|POST /JobService/hs/jobservice/screeningrules/
+// POST заменяет настройки правил целиком.
+func screeningRulesHandler(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	admin, ok := requestAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	dataMu.Lock()
+	defer dataMu.Unlock()
+
+	if r.Method == http.MethodPost {
+		var cfg ScreeningConfig
+		if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+			writeError(w, http.StatusBadRequest, "некорректный JSON")
+			return
+		}
+		rules, err := buildScreeningRules(cfg)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		audit(r, admin, AuditScreeningUpdate, screeningConfigFile, screeningConfig, cfg)
+		screeningConfig, screeningRules = cfg, rules
+		requestLogger(r).Info("правила проверки текстов изменены")
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(screeningConfig)
+}
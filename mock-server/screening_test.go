@@ -0,0 +1,45 @@
+package main
+
+import "testing"
+
+func TestFindLink(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"Подробности на сайте пример.рф", "пример.рф"},
+		{"Сайт компании: компания.рф.", "компания.рф"},
+		{"пример.рф", "пример.рф"},
+		{"Пишите: company.ru или звоните", "company.ru"},
+		{"Вакансии на company.ru/jobs", "company.ru/jobs"},
+		{"Резюме на hr@mail.ru", "mail.ru"},
+		{"Откликайтесь на https://example.com/jobs?id=1, ждем", "https://example.com/jobs?id=1"},
+		{"www.Example.org", "www.Example.org"},
+		{"Канал t.me/dvfu_jobs", "t.me/dvfu_jobs"},
+		{"Пишите @recruiter_bot", "@recruiter_bot"},
+		{"Опыт с ASP.NET и Node.js", ""},
+		{"Знание .NET обязательно", ""},
+		{"Проект example.community", ""},
+		{"Версия 2.1.ru", ""},
+		{"Без ссылок", ""},
+	}
+	for _, tt := range tests {
+		if got := findLink(tt.text); got != tt.want {
+			t.Errorf("findLink(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestContactsRuleLinks(t *testing.T) {
+	s := ScreeningSubject{Kind: ScreeningVacancy, Texts: []string{"Разработчик ASP.NET", "Подробнее на вакансии.рф"}}
+	found := contactsRule{}.Check(s)
+	if len(found) != 1 || found[0] != "внешняя ссылка в тексте: вакансии.рф" {
+		t.Errorf("Check = %q", found)
+	}
+	if found := (contactsRule{allowLinks: true}).Check(s); len(found) != 0 {
+		t.Errorf("Check with allowLinks = %q", found)
+	}
+	s.Kind = ScreeningRequest
+	if found := (contactsRule{}).Check(s); len(found) != 0 {
+		t.Errorf("Check for a request = %q", found)
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Полнотекстовый поиск по вакансиям: слова приводятся к основе stemRussian,
+// для слов запроса, которых нет в индексе, подбираются основы с опечаткой
+// или с общим началом ("программист" - "программирован").
+
+// Вес совпадения в зависимости от поля вакансии
+var searchFieldWeights = map[string]float64{
+	"Title":        3,
+	"TypesOfWork":  2.5,
+	"Organization": 2,
+	"Description":  1,
+}
+
+// searchHit - результат поиска: номер вакансии и ее релевантность
+type searchHit struct {
+	Number string
+	Score  float64
+}
+
+// searchIndex - обратный индекс: основа слова -> номер вакансии -> вес
+type searchIndex struct {
+	postings map[string]map[string]float64
+	docs     map[string][]string
+}
+
+var vacancyIndex = newSearchIndex()
+
+func init() {
+	for _, v := range vacancies {
+		vacancyIndex.Add(v)
+	}
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		postings: map[string]map[string]float64{},
+		docs:     map[string][]string{},
+	}
+}
+
+// tokenize разбивает текст на слова. Символы "+" и "#" считаются частью слова, чтобы не терять "C++" и "C#".
+func tokenize(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '+' && r != '#'
+	})
+}
+
+// Add добавляет вакансию в индекс, заменяя прежнюю версию с тем же номером
+func (idx *searchIndex) Add(v Vacancy) {
+	idx.Remove(v.Number)
+
+	fields := map[string]string{
+		"Title":        v.Title,
+		"TypesOfWork":  strings.Join(v.TypesOfWork, " "),
+		"Organization": v.Organization,
+		"Description":  v.Description,
+	}
+	for field, text := range fields {
+		for _, token := range tokenize(text) {
+			stem := stemRussian(token)
+			if idx.postings[stem] == nil {
+				idx.postings[stem] = map[string]float64{}
+			}
+			if _, ok := idx.postings[stem][v.Number]; !ok {
+				idx.docs[v.Number] = append(idx.docs[v.Number], stem)
+			}
+			idx.postings[stem][v.Number] += searchFieldWeights[field]
+		}
+	}
+}
+
+// Remove удаляет вакансию из индекса
+func (idx *searchIndex) Remove(number string) {
+	for _, stem := range idx.docs[number] {
+		delete(idx.postings[stem], number)
+		if len(idx.postings[stem]) == 0 {
+			delete(idx.postings, stem)
+		}
+	}
+	delete(idx.docs, number)
+}
+
+// Search возвращает вакансии, подходящие под все слова запроса, по убыванию релевантности
+func (idx *searchIndex) Search(query string) []searchHit {
+	tokens := tokenize(query)
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	total := float64(len(idx.docs))
+	var scores map[string]float64
+	for _, token := range tokens {
+		termScores := map[string]float64{}
+		for stem, similarity := range idx.similarStems(stemRussian(token)) {
+			postings := idx.postings[stem]
+			idf := math.Log(1 + total/float64(len(postings)))
+			for number, weight := range postings {
+				score := similarity * weight * idf
+				termScores[number] = math.Max(termScores[number], score)
+			}
+		}
+
+		// Все слова запроса должны встретиться в вакансии
+		if scores == nil {
+			scores = termScores
+			continue
+		}
+		for number := range scores {
+			if s, ok := termScores[number]; ok {
+				scores[number] += s
+			} else {
+				delete(scores, number)
+			}
+		}
+	}
+
+	hits := make([]searchHit, 0, len(scores))
+	for number, score := range scores {
+		hits = append(hits, searchHit{Number: number, Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Number < hits[j].Number
+	})
+	return hits
+}
+
+// similarStems подбирает основы из индекса, похожие на основу слова запроса, со степенью сходства от 0 до 1
+func (idx *searchIndex) similarStems(stem string) map[string]float64 {
+	result := map[string]float64{}
+	if _, ok := idx.postings[stem]; ok {
+		result[stem] = 1
+	}
+
+	q := []rune(stem)
+	if len(q) < 4 {
+		return result
+	}
+	maxEdits := 1
+	if len(q) >= 8 {
+		maxEdits = 2
+	}
+
+	for candidate := range idx.postings {
+		if candidate == stem {
+			continue
+		}
+		c := []rune(candidate)
+
+		// Однокоренные слова: общее начало не короче 5 букв и 70% более короткой основы
+		prefix := commonPrefixLen(q, c)
+		if prefix >= 5 && float64(prefix) >= 0.7*float64(min(len(q), len(c))) {
+			result[candidate] = math.Max(result[candidate], 0.6*float64(prefix)/float64(max(len(q), len(c))))
+		}
+
+		// Опечатки
+		if abs(len(c)-len(q)) <= maxEdits {
+			if d := editDistance(q, c, maxEdits); d <= maxEdits {
+				result[candidate] = math.Max(result[candidate], 1-0.25*float64(d))
+			}
+		}
+	}
+	return result
+}
+
+func commonPrefixLen(a, b []rune) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// editDistance - расстояние Дамерау-Левенштейна (с перестановкой соседних букв).
+// Если расстояние заведомо больше limit, возвращается limit+1.
+func editDistance(a, b []rune, limit int) int {
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSearchIndex(t *testing.T) {
+	idx := newSearchIndex()
+	idx.Add(Vacancy{Number: "1", Title: "Программирование на Go", Description: "Разработка сервисов"})
+	idx.Add(Vacancy{Number: "2", Title: "Волонтер", Description: "Помощь на мероприятиях"})
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		// Основы "программист" и "программирован" различаются, вакансия
+		// находится по общему началу
+		{"программист", []string{"1"}},
+		{"программирования", []string{"1"}},
+		{"волонтеры", []string{"2"}},
+		{"програмирование", []string{"1"}},
+		{"программист волонтер", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		hits := idx.Search(tt.query)
+		var got []string
+		for _, h := range hits {
+			got = append(got, h.Number)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Search(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+
+	idx.Remove("1")
+	if hits := idx.Search("программирование"); len(hits) != 0 {
+		t.Errorf("Search after Remove = %v", hits)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+)
+
+// Seed - начальные данные сервера. Разделы, которых нет в файле,
+// остаются встроенными.
+type Seed struct {
+	Vacancies     []Vacancy               `json:"Vacancies"`
+	Requests      []Request               `json:"Requests"`
+	Notifies      []Notify                `json:"Notifies"`
+	Accounts      map[string]Account      `json:"Accounts"`
+	Organizations map[string]Organization `json:"Organizations"`
+}
+
+// loadSeed заменяет встроенные данные данными из файла. Вызывается до запуска сервера.
+func loadSeed(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var seed Seed
+	if err := json.Unmarshal(data, &seed); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	if seed.Vacancies != nil {
+		lastNumber := 0
+		for _, v := range seed.Vacancies {
+			n, err := strconv.Atoi(v.Number)
+			if err != nil {
+				return fmt.Errorf("%s: некорректный номер вакансии %q", path, v.Number)
+			}
+			lastNumber = max(lastNumber, n)
+		}
+		vacancies = seed.Vacancies
+		lastVacancyNumber = lastNumber
+		vacancyIndex = newSearchIndex()
+		for _, v := range vacancies {
+			vacancyIndex.Add(v)
+		}
+		invalidateTagStats()
+	}
+	if seed.Requests != nil {
+		lastID := 0
+		seen := map[string]bool{}
+		for _, req := range seed.Requests {
+			if req.ID == "" {
+				continue
+			}
+			n, err := strconv.Atoi(req.ID)
+			if err != nil || seen[req.ID] {
+				return fmt.Errorf("%s: некорректный или повторный номер отклика %q", path, req.ID)
+			}
+			seen[req.ID] = true
+			lastID = max(lastID, n)
+		}
+		// Отклики без номеров, как в старых выгрузках, нумеруются по порядку
+		for i := range seed.Requests {
+			if seed.Requests[i].ID == "" {
+				lastID++
+				seed.Requests[i].ID = formatNumber(lastID)
+			}
+		}
+		requests = seed.Requests
+		lastRequestID = lastID
+	}
+	if seed.Notifies != nil {
+		notifies = seed.Notifies
+	}
+	if seed.Accounts != nil {
+		accountsDB = seed.Accounts
+	}
+	if seed.Organizations != nil {
+		organizations = seed.Organizations
+	}
+
+	slog.Info("начальные данные загружены", "path", path,
+		"vacancies", len(vacancies), "requests", len(requests), "accounts", len(accountsDB))
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// readTimeout рассчитан на загрузку резюме размером maxAttachmentSize
+// по медленному каналу, остальные ограничения сервера задает shared.
+const readTimeout = 30 * time.Second
+
+// maxJSONBodySize ограничивает тело JSON-запросов
+const maxJSONBodySize = 1 << 20
+
+// bodyLimitMiddleware ограничивает размер тела запроса: JSON - maxJSONBodySize,
+// multipart/form-data - размер резюме с запасом на поля формы.
+func bodyLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var limit int64 = maxJSONBodySize
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			limit = maxAttachmentSize + 1<<20
+		}
+		if r.ContentLength > limit {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("тело запроса не должно превышать %d КБ", limit>>10))
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// jsonWriter откладывает отправку заголовков до первой записи тела, чтобы
+// выставить Content-Type: application/json только ответам с телом.
+type jsonWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *jsonWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *jsonWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		w.flushHeader()
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *jsonWriter) flushHeader() {
+	w.wroteHeader = true
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+func (w *jsonWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// jsonContentTypeMiddleware отмечает ответы с телом как JSON, если обработчик
+// не указал другой тип. Пустые ответы уходят без Content-Type.
+func jsonContentTypeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		jw := &jsonWriter{ResponseWriter: w}
+		next.ServeHTTP(jw, r)
+		if !jw.wroteHeader && jw.status != 0 {
+			jw.flushHeader()
+		}
+	})
+}
@@ -0,0 +1,156 @@
+package main
+
+import "strings"
+
+// Стеммер для русского языка по алгоритму Snowball:
+// https://snowballstem.org/algorithms/russian/stemmer.html
+
+var (
+	ruPerfectiveGerund1 = []string{"вшись", "вши", "в"}
+	ruPerfectiveGerund2 = []string{"ившись", "ывшись", "ивши", "ывши", "ив", "ыв"}
+	ruAdjective         = []string{
+		"ими", "ыми", "его", "ого", "ему", "ому",
+		"ее", "ие", "ые", "ое", "ей", "ий", "ый", "ой", "ем", "им", "ым", "ом",
+		"их", "ых", "ую", "юю", "ая", "яя", "ою", "ею",
+	}
+	ruParticiple1 = []string{"ем", "нн", "вш", "ющ", "щ"}
+	ruParticiple2 = []string{"ивш", "ывш", "ующ"}
+	ruReflexive   = []string{"ся", "сь"}
+	ruVerb1       = []string{
+		"ете", "йте", "ешь", "нно",
+		"ла", "на", "ли", "ем", "ло", "но", "ет", "ют", "ны", "ть",
+		"й", "л", "н",
+	}
+	ruVerb2 = []string{
+		"ейте", "уйте",
+		"ила", "ыла", "ена", "ите", "или", "ыли", "ило", "ыло", "ено", "ует", "уют", "ены", "ить", "ыть", "ишь",
+		"ей", "уй", "ил", "ыл", "им", "ым", "ен", "ят", "ит", "ыт", "ую",
+		"ю",
+	}
+	ruNoun = []string{
+		"иями",
+		"ями", "ами", "ией", "иям", "ием", "иях",
+		"ев", "ов", "ие", "ье", "еи", "ии", "ей", "ой", "ий", "ям", "ем", "ам", "ом", "ах", "ях", "ию", "ью", "ия", "ья",
+		"а", "е", "и", "й", "о", "у", "ы", "ь", "ю", "я",
+	}
+	ruSuperlative  = []string{"ейше", "ейш"}
+	ruDerivational = []string{"ость", "ост"}
+)
+
+func isRuVowel(r rune) bool {
+	return strings.ContainsRune("аеиоуыэюя", r)
+}
+
+// stemRussian возвращает основу русского слова в нижнем регистре.
+// Слова не на кириллице возвращаются без изменений.
+func stemRussian(word string) string {
+	w := []rune(strings.ReplaceAll(strings.ToLower(word), "ё", "е"))
+
+	// RV - часть слова после первой гласной, R1 - после первой согласной,
+	// следующей за гласной, R2 - то же внутри R1
+	rv := len(w)
+	for i, r := range w {
+		if isRuVowel(r) {
+			rv = i + 1
+			break
+		}
+	}
+	if rv >= len(w) {
+		return string(w)
+	}
+	r1 := regionAfterVowelConsonant(w, 0)
+	r2 := regionAfterVowelConsonant(w, r1)
+
+	// Шаг 1
+	if end, ok := ruRemoveGrouped(w, rv, ruPerfectiveGerund1, ruPerfectiveGerund2); ok {
+		w = w[:end]
+	} else {
+		if end, ok := ruRemove(w, rv, ruReflexive); ok {
+			w = w[:end]
+		}
+		if end, ok := ruRemoveAdjectival(w, rv); ok {
+			w = w[:end]
+		} else if end, ok := ruRemoveGrouped(w, rv, ruVerb1, ruVerb2); ok {
+			w = w[:end]
+		} else if end, ok := ruRemove(w, rv, ruNoun); ok {
+			w = w[:end]
+		}
+	}
+
+	// Шаг 2
+	if end, ok := ruRemove(w, rv, []string{"и"}); ok {
+		w = w[:end]
+	}
+
+	// Шаг 3
+	if end, ok := ruRemove(w, r2, ruDerivational); ok {
+		w = w[:end]
+	}
+
+	// Шаг 4
+	if end, ok := ruRemove(w, rv, []string{"нн"}); ok {
+		w = w[:end+1]
+	} else if end, ok := ruRemove(w, rv, ruSuperlative); ok {
+		w = w[:end]
+		if end, ok := ruRemove(w, rv, []string{"нн"}); ok {
+			w = w[:end+1]
+		}
+	} else if end, ok := ruRemove(w, rv, []string{"ь"}); ok {
+		w = w[:end]
+	}
+
+	return string(w)
+}
+
+// regionAfterVowelConsonant возвращает начало региона после первой пары "гласная, согласная",
+// начиная с позиции from
+func regionAfterVowelConsonant(w []rune, from int) int {
+	for i := from + 1; i < len(w); i++ {
+		if !isRuVowel(w[i]) && isRuVowel(w[i-1]) {
+			return i + 1
+		}
+	}
+	return len(w)
+}
+
+// ruRemove ищет самое длинное из окончаний, целиком лежащее в регионе с позиции region.
+// Возвращает длину слова без окончания.
+func ruRemove(w []rune, region int, endings []string) (int, bool) {
+	best := -1
+	for _, e := range endings {
+		er := []rune(e)
+		start := len(w) - len(er)
+		if start < region || start < 0 {
+			continue
+		}
+		if string(w[start:]) == e && (best < 0 || start < best) {
+			best = start
+		}
+	}
+	return best, best >= 0
+}
+
+// ruRemoveGrouped ищет окончание из группы 1, перед которым стоит "а" или "я" (она остается),
+// или из группы 2. Из найденных выбирается самое длинное.
+func ruRemoveGrouped(w []rune, region int, group1, group2 []string) (int, bool) {
+	best := -1
+	if end, ok := ruRemove(w, region, group1); ok && end-1 >= region && (w[end-1] == 'а' || w[end-1] == 'я') {
+		best = end
+	}
+	if end, ok := ruRemove(w, region, group2); ok && (best < 0 || end < best) {
+		best = end
+	}
+	return best, best >= 0
+}
+
+// ruRemoveAdjectival удаляет окончание прилагательного вместе с суффиксом причастия, если он есть
+func ruRemoveAdjectival(w []rune, region int) (int, bool) {
+	end, ok := ruRemove(w, region, ruAdjective)
+	if !ok {
+		return 0, false
+	}
+	if pend, ok := ruRemoveGrouped(w[:end], region, ruParticiple1, ruParticiple2); ok {
+		return pend, true
+	}
+	return end, true
+}
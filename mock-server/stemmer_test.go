@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+// Ожидаемые основы совпадают с русским стеммером Snowball
+func TestStemRussian(t *testing.T) {
+	tests := []struct {
+		word, want string
+	}{
+		{"программирование", "программирован"},
+		{"красивейшими", "красив"},
+		{"ёжиками", "ежик"},
+		{"Вакансии", "ваканс"},
+		{"студентов", "студент"},
+		{"работа", "работ"},
+		{"разработчик", "разработчик"},
+		{"программист", "программист"},
+		{"C++", "c++"},
+	}
+	for _, tt := range tests {
+		if got := stemRussian(tt.word); got != tt.want {
+			t.Errorf("stemRussian(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,463 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Tag - направление работ в справочнике. Parent задает категорию верхнего уровня
+// (например, "Программирование" для "Go"), Synonyms - другие написания,
+// которые приводятся к Name при создании вакансий и в фильтрах.
+type Tag struct {
+	Name     string   `json:"Name"`
+	Parent   string   `json:"Parent"`
+	Synonyms []string `json:"Synonyms"`
+}
+
+var tags = []Tag{
+	{Name: "Наука"},
+	{Name: "Медицина"},
+	{Name: "Литература", Parent: "Творчество"},
+	{Name: "Технологии"},
+	{Name: "Творчество"},
+	{Name: "Программирование", Parent: "Технологии", Synonyms: []string{"Разработка"}},
+	{Name: "Алгоритмы", Parent: "Программирование"},
+	{Name: "ICPC", Parent: "Алгоритмы", Synonyms: []string{"Олимпиадное программирование"}},
+	{Name: "Помощь пожилым", Parent: "Общественная польза"},
+	{Name: "Общественная польза", Synonyms: []string{"Волонтерство"}},
+	{Name: "Backend", Parent: "Программирование", Synonyms: []string{"Бэкенд"}},
+	{Name: "Frontend", Parent: "Программирование", Synonyms: []string{"Фронтенд"}},
+	{Name: "Go", Parent: "Программирование", Synonyms: []string{"Golang"}},
+	{Name: "API", Parent: "Программирование"},
+	{Name: "Обучение", Synonyms: []string{"Преподавание"}},
+	{Name: "C++", Parent: "Программирование", Synonyms: []string{"cpp"}},
+	{Name: "Python", Parent: "Программирование"},
+	{Name: "Web", Parent: "Программирование", Synonyms: []string{"Веб"}},
+	{Name: "Mobile", Parent: "Программирование", Synonyms: []string{"Мобильная разработка"}},
+	{Name: "IoT", Parent: "Технологии", Synonyms: []string{"Интернет вещей"}},
+	{Name: "Дизайн", Parent: "Творчество"},
+	{Name: "Редакция", Parent: "Литература"},
+	{Name: "Культура"},
+	{Name: "Экология", Parent: "Общественная польза"},
+	{Name: "Помощь людям", Parent: "Общественная польза"},
+	{Name: "IT", Parent: "Технологии", Synonyms: []string{"ИТ"}},
+	{Name: "Кибербезопасность", Parent: "Технологии", Synonyms: []string{"Кибер", "Информационная безопасность"}},
+}
+
+// Кириллические буквы, совпадающие по начертанию с латинскими: "С++" и "C++" - один тег
+var homoglyphs = strings.NewReplacer(
+	"а", "a", "в", "b", "е", "e", "к", "k", "м", "m", "н", "h",
+	"о", "o", "р", "p", "с", "c", "т", "t", "у", "y", "х", "x",
+)
+
+// tagKey - ключ для сравнения написаний тега без учета регистра, пробелов по краям и кириллицы/латиницы
+func tagKey(name string) string {
+	return homoglyphs.Replace(strings.ToLower(strings.TrimSpace(name)))
+}
+
+// findTag возвращает индекс тега с таким названием или синонимом либо -1
+func findTag(name string) int {
+	key := tagKey(name)
+	for i, t := range tags {
+		if tagKey(t.Name) == key {
+			return i
+		}
+	}
+	for i, t := range tags {
+		if slices.ContainsFunc(t.Synonyms, func(s string) bool { return tagKey(s) == key }) {
+			return i
+		}
+	}
+	return -1
+}
+
+// canonicalTag приводит написание тега к названию из справочника
+func canonicalTag(name string) (string, bool) {
+	if i := findTag(name); i >= 0 {
+		return tags[i].Name, true
+	}
+	return "", false
+}
+
+// canonicalTags приводит список тегов к названиям из справочника без повторов.
+// Возвращает ошибку со списком неизвестных тегов.
+func canonicalTags(names []string) ([]string, error) {
+	result := make([]string, 0, len(names))
+	var unknown []string
+	for _, name := range names {
+		canonical, ok := canonicalTag(name)
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		if !slices.Contains(result, canonical) {
+			result = append(result, canonical)
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("неизвестные направления работ: %s", strings.Join(unknown, ", "))
+	}
+	return result, nil
+}
+
+// tagWithDescendants возвращает тег и все вложенные в него теги
+func tagWithDescendants(name string) []string {
+	result := []string{name}
+	for i := 0; i < len(result); i++ {
+		for _, t := range tags {
+			if t.Parent == result[i] && !slices.Contains(result, t.Name) {
+				result = append(result, t.Name)
+			}
+		}
+	}
+	return result
+}
+
+// isAncestorTag сообщает, вложен ли тег name в ancestor (или совпадает с ним)
+func isAncestorTag(ancestor, name string) bool {
+	return slices.Contains(tagWithDescendants(ancestor), name)
+}
+
+// validTagName проверяет, что название тега непустое и состоит из печатных символов
+func validTagName(name string) bool {
+	return strings.TrimSpace(name) != "" && !strings.ContainsFunc(name, func(r rune) bool {
+		return !unicode.IsPrint(r) || r == ','
+	})
+}
+
+// replaceTag заменяет тег from на to в списке без повторов
+func replaceTag(list []string, from, to string) []string {
+	result := make([]string, 0, len(list))
+	for _, t := range list {
+		if t == from {
+			t = to
+		}
+		if !slices.Contains(result, t) {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// rewriteTag заменяет тег from на to во всех вакансиях, в том числе ожидающих
+// модерации, анкетах и подписках. Вызывающий держит dataMu.
+func rewriteTag(from, to string) int {
+	changed := 0
+	for i, v := range vacancies {
+		if slices.Contains(v.TypesOfWork, from) {
+			vacancies[i].TypesOfWork = replaceTag(v.TypesOfWork, from, to)
+			vacancyIndex.Add(vacancies[i])
+			changed++
+		}
+	}
+	for number, v := range closedVacancies {
+		if slices.Contains(v.TypesOfWork, from) {
+			v.TypesOfWork = replaceTag(v.TypesOfWork, from, to)
+			closedVacancies[number] = v
+		}
+	}
+	for i, m := range moderationQueue {
+		if slices.Contains(m.Vacancy.TypesOfWork, from) {
+			moderationQueue[i].Vacancy.TypesOfWork = replaceTag(m.Vacancy.TypesOfWork, from, to)
+		}
+	}
+	for student, p := range profiles {
+		if slices.Contains(p.Skills, from) {
+			p.Skills = replaceTag(p.Skills, from, to)
+			profiles[student] = p
+		}
+	}
+	for i, s := range savedSearches {
+		if list, ok := s.Params["typesofwork"]; ok {
+			s.Params["typesofwork"] = strings.Join(replaceTag(splitTypesOfWork(list), from, to), ",")
+			savedSearches[i] = s
+		}
+	}
+	for i := range tags {
+		if tags[i].Parent == from {
+			tags[i].Parent = to
+		}
+	}
+	invalidateTagStats()
+	return changed
+}
+
+// 4. Get Tags - GET /JobService/hs/jobservice/tags
+// По умолчанию возвращает список названий; с параметром full=true - справочник с категориями и синонимами,
+// с параметром stats=true - количество открытых вакансий и диапазон зарплат по каждому тегу.
+func getTags(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	dataMu.RLock()
+	defer dataMu.RUnlock()
+
+	requestLogger(r).Debug("возвращены теги", "count", len(tags))
+	w.WriteHeader(http.StatusOK)
+	switch {
+	case r.URL.Query().Get("stats") == "true":
+		json.NewEncoder(w).Encode(tagStats(time.Now()))
+		return
+	case r.URL.Query().Get("full") == "true":
+		json.NewEncoder(w).Encode(tags)
+		return
+	}
+	names := make([]string, 0, len(tags))
+	for _, t := range tags {
+		names = append(names, t.Name)
+	}
+	json.NewEncoder(w).Encode(names)
+}
+
+// tagRequest - тело запросов /tag и /updatetag
+type tagRequest struct {
+	Name     string   `json:"name"`
+	Parent   string   `json:"parent"`
+	Synonyms []string `json:"synonyms"`
+}
+
+// checkTagRequest проверяет родителя и синонимы тега name. Вызывающий держит dataMu.
+func checkTagRequest(name string, data tagRequest) (string, error) {
+	parent := ""
+	if data.Parent != "" {
+		var ok bool
+		if parent, ok = canonicalTag(data.Parent); !ok {
+			return "", fmt.Errorf("родительский тег не найден: %s", data.Parent)
+		}
+		if isAncestorTag(name, parent) {
+			return "", fmt.Errorf("тег не может быть вложен сам в себя")
+		}
+	}
+	for _, s := range data.Synonyms {
+		if !validTagName(s) {
+			return "", fmt.Errorf("некорректный синоним: %q", s)
+		}
+		if i := findTag(s); i >= 0 && tags[i].Name != name {
+			return "", fmt.Errorf("синоним %s уже относится к тегу %s", s, tags[i].Name)
+		}
+	}
+	return parent, nil
+}
+
+// 30. Create Tag - POST /JobService/hs/jobservice/tag
+// Справочник изменяют только администраторы.
+func createTag(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	admin, ok := requestAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var data tagRequest
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		writeError(w, http.StatusBadRequest, "некорректный JSON")
+		return
+	}
+	data.Name = strings.TrimSpace(data.Name)
+	if !validTagName(data.Name) {
+		writeError(w, http.StatusBadRequest, "некорректное название тега")
+		return
+	}
+
+	dataMu.Lock()
+	defer dataMu.Unlock()
+
+	if i := findTag(data.Name); i >= 0 {
+		writeError(w, http.StatusConflict, "тег уже существует: "+tags[i].Name)
+		return
+	}
+	parent, err := checkTagRequest(data.Name, data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	t := Tag{Name: data.Name, Parent: parent, Synonyms: data.Synonyms}
+	tags = append(tags, t)
+	invalidateTagStats()
+	audit(r, admin, AuditTagCreate, t.Name, nil, t)
+
+	requestLogger(r).Info("тег создан", "tag", t.Name)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(t)
+}
+
+// 31. Update Tag - POST /JobService/hs/jobservice/updatetag/?name=Name
+// Заменяет родителя и список синонимов тега.
+func updateTag(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	admin, ok := requestAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var data tagRequest
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		writeError(w, http.StatusBadRequest, "некорректный JSON")
+		return
+	}
+
+	dataMu.Lock()
+	defer dataMu.Unlock()
+
+	i := findTag(r.URL.Query().Get("name"))
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "тег не найден")
+		return
+	}
+	parent, err := checkTagRequest(tags[i].Name, data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	before := tags[i]
+	tags[i].Parent = parent
+	tags[i].Synonyms = data.Synonyms
+	invalidateTagStats()
+	audit(r, admin, AuditTagUpdate, tags[i].Name, before, tags[i])
+
+	requestLogger(r).Info("тег изменен", "tag", tags[i].Name)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tags[i])
+}
+
+// 32. Rename Tag - POST /JobService/hs/jobservice/renametag/?name=Name&newname=NewName
+// Старое название остается синонимом, вакансии и анкеты переписываются.
+func renameTag(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	admin, ok := requestAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	newName := strings.TrimSpace(r.URL.Query().Get("newname"))
+	if !validTagName(newName) {
+		writeError(w, http.StatusBadRequest, "некорректное новое название тега")
+		return
+	}
+
+	dataMu.Lock()
+	defer dataMu.Unlock()
+
+	i := findTag(r.URL.Query().Get("name"))
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "тег не найден")
+		return
+	}
+	if j := findTag(newName); j >= 0 && j != i {
+		writeError(w, http.StatusConflict, "тег уже существует: "+tags[j].Name+", используйте объединение")
+		return
+	}
+
+	before := tags[i]
+	before.Synonyms = slices.Clone(before.Synonyms)
+	oldName := tags[i].Name
+	tags[i].Name = newName
+	tags[i].Synonyms = slices.DeleteFunc(tags[i].Synonyms, func(s string) bool { return tagKey(s) == tagKey(newName) })
+	if tagKey(oldName) != tagKey(newName) {
+		tags[i].Synonyms = append(tags[i].Synonyms, oldName)
+	}
+	changed := rewriteTag(oldName, newName)
+	audit(r, admin, AuditTagRename, oldName, before, tags[i])
+
+	requestLogger(r).Info("тег переименован", "tag", oldName, "new_name", newName, "vacancies", changed)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{"tag": tags[i], "vacancies": changed})
+}
+
+// 33. Merge Tags - POST /JobService/hs/jobservice/mergetag/?from=Name&to=Name
+// Тег from удаляется, его название и синонимы становятся синонимами тега to.
+func mergeTag(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	admin, ok := requestAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	dataMu.Lock()
+	defer dataMu.Unlock()
+
+	from := findTag(r.URL.Query().Get("from"))
+	to := findTag(r.URL.Query().Get("to"))
+	if from < 0 || to < 0 {
+		writeError(w, http.StatusNotFound, "тег не найден")
+		return
+	}
+	if from == to {
+		writeError(w, http.StatusBadRequest, "нельзя объединить тег с самим собой")
+		return
+	}
+	if isAncestorTag(tags[from].Name, tags[to].Name) {
+		writeError(w, http.StatusBadRequest, "нельзя объединить категорию с вложенным в нее тегом")
+		return
+	}
+
+	source := tags[from]
+	tags[to].Synonyms = append(tags[to].Synonyms, source.Name)
+	tags[to].Synonyms = append(tags[to].Synonyms, source.Synonyms...)
+	target := tags[to]
+	tags = slices.Delete(tags, from, from+1)
+	changed := rewriteTag(source.Name, target.Name)
+	audit(r, admin, AuditTagMerge, source.Name, source, target)
+
+	requestLogger(r).Info("теги объединены", "tag", source.Name, "target", target.Name, "vacancies", changed)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{"tag": target, "vacancies": changed})
+}
+
+// 34. Delete Tag - POST /JobService/hs/jobservice/deletetag/?name=Name
+// Удалить можно только тег без вложенных тегов, который не используется в открытых вакансиях
+// и в вакансиях, ожидающих модерации.
+func deleteTag(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	admin, ok := requestAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	dataMu.Lock()
+	defer dataMu.Unlock()
+
+	i := findTag(r.URL.Query().Get("name"))
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "тег не найден")
+		return
+	}
+	name := tags[i].Name
+	if len(tagWithDescendants(name)) > 1 {
+		writeError(w, http.StatusConflict, "у тега есть вложенные теги")
+		return
+	}
+	if slices.ContainsFunc(vacancies, func(v Vacancy) bool { return slices.Contains(v.TypesOfWork, name) }) {
+		writeError(w, http.StatusConflict, "тег используется в вакансиях, используйте объединение")
+		return
+	}
+	if slices.ContainsFunc(moderationQueue, func(m ModerationItem) bool {
+		return m.Status == ModerationPending && slices.Contains(m.Vacancy.TypesOfWork, name)
+	}) {
+		writeError(w, http.StatusConflict, "тег используется в вакансиях на модерации, используйте объединение")
+		return
+	}
+	before := tags[i]
+	tags = slices.Delete(tags, i, i+1)
+	invalidateTagStats()
+	audit(r, admin, AuditTagDelete, name, before, nil)
+	for student, p := range profiles {
+		if slices.Contains(p.Skills, name) {
+			p.Skills = slices.DeleteFunc(p.Skills, func(s string) bool { return s == name })
+			profiles[student] = p
+		}
+	}
+
+	requestLogger(r).Info("тег удален", "tag", name)
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// TagStats - статистика открытых вакансий по тегу
+type TagStats struct {
+	Name          string `json:"Name"`
+	OpenVacancies int    `json:"OpenVacancies"`
+	// OpenVacanciesWithNested учитывает и вакансии с вложенными тегами, как фильтр typesofwork
+	OpenVacanciesWithNested int `json:"OpenVacanciesWithNested"`
+	SalaryMin               int `json:"SalaryMin"`
+	SalaryMax               int `json:"SalaryMax"`
+}
+
+// Статистика пересчитывается при изменении вакансий или справочника,
+// а также не реже tagStatsTTL, чтобы учесть вакансии с истекшим сроком.
+const tagStatsTTL = time.Minute
+
+var tagStatsCache struct {
+	sync.Mutex
+	stats    []TagStats
+	computed time.Time
+}
+
+// invalidateTagStats сбрасывает кэш статистики тегов
+func invalidateTagStats() {
+	tagStatsCache.Lock()
+	tagStatsCache.stats = nil
+	tagStatsCache.Unlock()
+}
+
+// tagStats возвращает статистику по всем тегам справочника. Вызывающий держит dataMu.
+func tagStats(now time.Time) []TagStats {
+	tagStatsCache.Lock()
+	defer tagStatsCache.Unlock()
+
+	if tagStatsCache.stats != nil && now.Sub(tagStatsCache.computed) < tagStatsTTL {
+		return tagStatsCache.stats
+	}
+
+	stats := make([]TagStats, 0, len(tags))
+	for _, t := range tags {
+		s := TagStats{Name: t.Name}
+		nested := tagWithDescendants(t.Name)
+		for _, v := range vacancies {
+			if !isOpenVacancy(v, now) {
+				continue
+			}
+			if slices.ContainsFunc(v.TypesOfWork, func(name string) bool { return slices.Contains(nested, name) }) {
+				s.OpenVacanciesWithNested++
+			}
+			if !slices.Contains(v.TypesOfWork, t.Name) {
+				continue
+			}
+			if s.OpenVacancies == 0 || v.Salary < s.SalaryMin {
+				s.SalaryMin = v.Salary
+			}
+			if v.Salary > s.SalaryMax {
+				s.SalaryMax = v.Salary
+			}
+			s.OpenVacancies++
+		}
+		stats = append(stats, s)
+	}
+
+	tagStatsCache.stats = stats
+	tagStatsCache.computed = now
+	return stats
+}
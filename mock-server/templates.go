@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// NotifyTemplate - шаблон сообщения кандидату, принадлежит организации.
+// В тексте допускаются подстановки из templatePlaceholders, например
+// "Уважаемый {student}! Одобрена ваша заявка на должность {title}."
+type NotifyTemplate struct {
+	ID           string `json:"ID"`
+	Organization string `json:"Organization"`
+	Name         string `json:"Name"`
+	Text         string `json:"Text"`
+}
+
+// Допустимые подстановки в тексте шаблона
+var templatePlaceholders = map[string]string{
+	"student":   "ФИО студента",
+	"title":     "Должность (название вакансии)",
+	"datebegin": "Дата начала работы",
+	"dateend":   "Дата окончания работы",
+	"location":  "Место встречи или работы",
+}
+
+var placeholderPattern = regexp.MustCompile(`\{([^{}]*)\}`)
+
+var notifyTemplates = []NotifyTemplate{
+	{
+		ID:           "000000001",
+		Organization: "f2742040-cdb4-11f0-ae42-38d57ae2c1c1",
+		Name:         "Одобрение заявки",
+		Text:         "Уважаемый {student}! \n Одобрена ваша заявка по вакансии на должность {title}. \n Сообщение от руководителя: Подходите в {location} {datebegin}",
+	},
+	{
+		ID:           "000000002",
+		Organization: "f2742040-cdb4-11f0-ae42-38d57ae2c1c1",
+		Name:         "Отказ",
+		Text:         "Уважаемый {student}! \n Спасибо за участие. К сожалению, мы выбрали другого кандидата. Удачи в поиске!",
+	},
+	{
+		ID:           "000000003",
+		Organization: "4c09ed30-cdb6-11f0-ae42-38d57ae2c1c1",
+		Name:         "Приглашение на собеседование",
+		Text:         "Уважаемый {student}! \n Одобрена заявка на должность {title}. Собеседование: {datebegin}, {location}",
+	},
+}
+
+var lastTemplateID = len(notifyTemplates)
+
+// validateTemplateText проверяет, что в тексте используются только известные подстановки
+func validateTemplateText(text string) error {
+	if strings.TrimSpace(text) == "" {
+		return fmt.Errorf("текст шаблона не может быть пустым")
+	}
+	for _, m := range placeholderPattern.FindAllStringSubmatch(text, -1) {
+		if _, ok := templatePlaceholders[m[1]]; !ok {
+			return fmt.Errorf("неизвестная подстановка {%s}", m[1])
+		}
+	}
+	return nil
+}
+
+// renderTemplate подставляет значения переменных в текст шаблона.
+// Если для какой-либо подстановки значение не задано, возвращается ошибка.
+func renderTemplate(text string, variables map[string]string) (string, error) {
+	var missing []string
+	rendered := placeholderPattern.ReplaceAllStringFunc(text, func(s string) string {
+		name := s[1 : len(s)-1]
+		value, ok := variables[name]
+		if !ok || value == "" {
+			missing = append(missing, name)
+			return s
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("не заданы значения подстановок: %s", strings.Join(missing, ", "))
+	}
+	return rendered, nil
+}
+
+// findTemplate возвращает индекс шаблона в notifyTemplates или -1
+func findTemplate(id string) int {
+	for i, t := range notifyTemplates {
+		if t.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// 12. Get Templates - GET /JobService/hs/jobservice/templates/?organization=GUID
+func getTemplates(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	organization, ok := requestOrganization(w, r, r.URL.Query().Get("organization"))
+	if !ok {
+		return
+	}
+	if organization == "" {
+		writeError(w, http.StatusBadRequest, "не указана организация")
+		return
+	}
+
+	dataMu.RLock()
+	result := make([]NotifyTemplate, 0)
+	for _, t := range notifyTemplates {
+		if t.Organization == organization {
+			result = append(result, t)
+		}
+	}
+	dataMu.RUnlock()
+
+	requestLogger(r).Debug("возвращены шаблоны", "count", len(result))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// 13. Create Template - POST /JobService/hs/jobservice/template
+func createTemplate(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	var data struct {
+		Organization string `json:"organization"`
+		Name         string `json:"name"`
+		Text         string `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		writeError(w, http.StatusBadRequest, "некорректный JSON")
+		return
+	}
+	organization, ok := requestOrganization(w, r, data.Organization)
+	if !ok {
+		return
+	}
+	data.Organization = organization
+	if data.Organization == "" || data.Name == "" {
+		writeError(w, http.StatusBadRequest, "не указаны организация или название шаблона")
+		return
+	}
+	if err := validateTemplateText(data.Text); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	dataMu.Lock()
+	lastTemplateID++
+	t := NotifyTemplate{
+		ID:           formatNumber(lastTemplateID),
+		Organization: data.Organization,
+		Name:         data.Name,
+		Text:         data.Text,
+	}
+	notifyTemplates = append(notifyTemplates, t)
+	dataMu.Unlock()
+	audit(r, accountLogin(t.Organization, ""), AuditTemplateCreate, t.ID, nil, t)
+
+	requestLogger(r).Info("шаблон создан", "template", t.ID)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(t)
+}
+
+// 14. Update Template - POST /JobService/hs/jobservice/updatetemplate/?id=ID
+func updateTemplate(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	id := r.URL.Query().Get("id")
+	var data struct {
+		Organization string `json:"organization"`
+		Name         string `json:"name"`
+		Text         string `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		writeError(w, http.StatusBadRequest, "некорректный JSON")
+		return
+	}
+	organization, ok := requestOrganization(w, r, data.Organization)
+	if !ok {
+		return
+	}
+	data.Organization = organization
+	if data.Text != "" {
+		if err := validateTemplateText(data.Text); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	dataMu.Lock()
+	defer dataMu.Unlock()
+
+	i := findTemplate(id)
+	if i < 0 || notifyTemplates[i].Organization != data.Organization {
+		writeError(w, http.StatusNotFound, "шаблон не найден")
+		return
+	}
+	before := notifyTemplates[i]
+	if data.Name != "" {
+		notifyTemplates[i].Name = data.Name
+	}
+	if data.Text != "" {
+		notifyTemplates[i].Text = data.Text
+	}
+	audit(r, accountLogin(data.Organization, ""), AuditTemplateUpdate, id, before, notifyTemplates[i])
+
+	requestLogger(r).Info("шаблон изменен", "template", id)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(notifyTemplates[i])
+}
+
+// 15. Delete Template - POST /JobService/hs/jobservice/deletetemplate/?id=ID&organization=GUID
+func deleteTemplate(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	id := r.URL.Query().Get("id")
+	organization, ok := requestOrganization(w, r, r.URL.Query().Get("organization"))
+	if !ok {
+		return
+	}
+
+	dataMu.Lock()
+	defer dataMu.Unlock()
+
+	i := findTemplate(id)
+	if i < 0 || notifyTemplates[i].Organization != organization {
+		writeError(w, http.StatusNotFound, "шаблон не найден")
+		return
+	}
+	before := notifyTemplates[i]
+	notifyTemplates = append(notifyTemplates[:i], notifyTemplates[i+1:]...)
+	audit(r, accountLogin(organization, ""), AuditTemplateDelete, id, before, nil)
+
+	requestLogger(r).Info("шаблон удален", "template", id)
+	w.WriteHeader(http.StatusOK)
+}
+
+// 16. Get Template Placeholders - GET /JobService/hs/jobservice/templateplaceholders
+func getTemplatePlaceholders(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(templatePlaceholders)
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Ticket - обращение пользователя, отправленное через /faq
+type Ticket struct {
+	ID       string        `json:"ID"`
+	Author   string        `json:"Author"`
+	Category string        `json:"Category"`
+	Text     string        `json:"Text"`
+	Status   string        `json:"Status"`
+	Assignee string        `json:"Assignee"`
+	Replies  []TicketReply `json:"Replies"`
+	Created  time.Time     `json:"Created"`
+	Updated  time.Time     `json:"Updated"`
+
+	// Адресат ответов: учетная запись автора на момент обращения
+	student, organization string
+}
+
+// TicketReply - ответ сотрудника на обращение
+type TicketReply struct {
+	Author string    `json:"Author"`
+	Text   string    `json:"Text"`
+	Date   time.Time `json:"Date"`
+}
+
+// Категории обращений
+const (
+	TicketComplaint  = "complaint"
+	TicketSuggestion = "suggestion"
+	TicketBug        = "bug"
+)
+
+// Статусы обращений
+const (
+	TicketOpen       = "open"
+	TicketInProgress = "in_progress"
+	TicketClosed     = "closed"
+)
+
+var ticketCategories = []string{TicketComplaint, TicketSuggestion, TicketBug}
+
+const maxTicketLength = 4000
+
+var (
+	tickets      = []Ticket{}
+	lastTicketID = 0
+)
+
+// findTicket возвращает индекс обращения или -1
+func findTicket(id string) int {
+	return slices.IndexFunc(tickets, func(t Ticket) bool { return t.ID == id })
+}
+
+// notifyTicketAuthor отправляет автору обращения уведомление. Вызывающий держит dataMu.
+func notifyTicketAuthor(t Ticket, text string) {
+	if t.student == "" && t.organization == "" {
+		return
+	}
+	addNotify(Notify{
+		Text:         fmt.Sprintf("Ответ на обращение №%s: %s", t.ID, text),
+		Date:         time.Now(),
+		Student:      t.student,
+		Organization: t.organization,
+		Ticket:       t.ID,
+	})
+}
+
+// 7. Send FAQ Suggestion - POST /JobService/hs/jobservice/faq
+// Обращение сохраняется как тикет. Без category считается предложением.
+func sendFAQ(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	var data struct {
+		Suggestion string `json:"suggestion"`
+		Login      string `json:"login"`
+		Category   string `json:"category"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		writeError(w, http.StatusBadRequest, "некорректный JSON")
+		return
+	}
+	text := strings.TrimSpace(data.Suggestion)
+	if text == "" {
+		writeError(w, http.StatusBadRequest, "текст обращения не может быть пустым")
+		return
+	}
+	if len([]rune(text)) > maxTicketLength {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("текст обращения не должен превышать %d символов", maxTicketLength))
+		return
+	}
+	if data.Category == "" {
+		data.Category = TicketSuggestion
+	}
+	if !slices.Contains(ticketCategories, data.Category) {
+		writeError(w, http.StatusBadRequest, "неизвестная категория обращения: "+data.Category)
+		return
+	}
+
+	login, ok := requestLogin(w, r, data.Login)
+	if !ok {
+		return
+	}
+	account, ok := lookupAccount(r.Context(), login)
+	if !ok {
+		writeError(w, http.StatusForbidden, "обращения принимаются только от зарегистрированных пользователей")
+		return
+	}
+
+	dataMu.Lock()
+	defer dataMu.Unlock()
+
+	lastTicketID++
+	now := time.Now()
+	t := Ticket{
+		ID:       formatNumber(lastTicketID),
+		Author:   login,
+		Category: data.Category,
+		Text:     text,
+		Status:   TicketOpen,
+		Replies:  []TicketReply{},
+		Created:  now,
+		Updated:  now,
+
+		student:      account.Student,
+		organization: account.Organization,
+	}
+	tickets = append(tickets, t)
+	audit(r, login, AuditTicketCreate, t.ID, nil, t)
+
+	requestLogger(r).Info("обращение сохранено", "ticket", t.ID, "category", t.Category)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(t)
+}
+
+// 35. Get My Tickets - GET /JobService/hs/jobservice/mytickets/?user=Login
+func getMyTickets(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	user, ok := requestLogin(w, r, r.URL.Query().Get("user"))
+	if !ok {
+		return
+	}
+
+	dataMu.RLock()
+	result := make([]Ticket, 0)
+	for _, t := range tickets {
+		if t.Author == user {
+			result = append(result, t)
+		}
+	}
+	dataMu.RUnlock()
+
+	requestLogger(r).Debug("возвращены обращения", "count", len(result))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// 36. Get Tickets - GET /JobService/hs/jobservice/tickets/?status=open&category=bug&assignee=Login
+// Только для сотрудников поддержки.
+func getTickets(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	q := r.URL.Query()
+	if _, ok := requestSupport(w, r); !ok {
+		return
+	}
+	status, category, assignee := q.Get("status"), q.Get("category"), q.Get("assignee")
+
+	dataMu.RLock()
+	result := make([]Ticket, 0)
+	for _, t := range tickets {
+		if (status == "" || t.Status == status) &&
+			(category == "" || t.Category == category) &&
+			(assignee == "" || t.Assignee == assignee) {
+			result = append(result, t)
+		}
+	}
+	dataMu.RUnlock()
+
+	requestLogger(r).Debug("возвращены обращения", "count", len(result))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// 37. Assign Ticket - POST /JobService/hs/jobservice/assignticket/?id=ID&assignee=Login
+// Без assignee обращение назначается на самого сотрудника.
+func assignTicket(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	q := r.URL.Query()
+	staff, ok := requestSupport(w, r)
+	if !ok {
+		return
+	}
+	assignee := q.Get("assignee")
+	if assignee == "" {
+		assignee = staff
+	}
+	if _, ok := staffAccount(r.Context(), assignee); !ok {
+		writeError(w, http.StatusBadRequest, "назначить обращение можно только на сотрудника")
+		return
+	}
+
+	dataMu.Lock()
+	defer dataMu.Unlock()
+
+	i := findTicket(q.Get("id"))
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "обращение не найдено")
+		return
+	}
+	if tickets[i].Status == TicketClosed {
+		writeError(w, http.StatusConflict, "обращение закрыто")
+		return
+	}
+	before := tickets[i]
+	tickets[i].Assignee = assignee
+	tickets[i].Status = TicketInProgress
+	tickets[i].Updated = time.Now()
+	audit(r, staff, AuditTicketAssign, tickets[i].ID, before, tickets[i])
+
+	requestLogger(r).Info("обращение назначено", "ticket", tickets[i].ID, "assignee", assignee)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tickets[i])
+}
+
+// 38. Reply Ticket - POST /JobService/hs/jobservice/replyticket/?id=ID
+// Автор обращения получает ответ в уведомлениях.
+func replyTicket(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	q := r.URL.Query()
+	staff, ok := requestSupport(w, r)
+	if !ok {
+		return
+	}
+	var data struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil || strings.TrimSpace(data.Text) == "" {
+		writeError(w, http.StatusBadRequest, "не указан текст ответа")
+		return
+	}
+
+	dataMu.Lock()
+	defer dataMu.Unlock()
+
+	i := findTicket(q.Get("id"))
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "обращение не найдено")
+		return
+	}
+	if tickets[i].Status == TicketClosed {
+		writeError(w, http.StatusConflict, "обращение закрыто")
+		return
+	}
+	before := tickets[i]
+	now := time.Now()
+	tickets[i].Replies = append(tickets[i].Replies, TicketReply{Author: staff, Text: data.Text, Date: now})
+	if tickets[i].Assignee == "" {
+		tickets[i].Assignee = staff
+	}
+	tickets[i].Status = TicketInProgress
+	tickets[i].Updated = now
+	notifyTicketAuthor(tickets[i], data.Text)
+	audit(r, staff, AuditTicketReply, tickets[i].ID, before, tickets[i])
+
+	requestLogger(r).Info("ответ на обращение отправлен", "ticket", tickets[i].ID)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tickets[i])
+}
+
+// 39. Close Ticket - POST /JobService/hs/jobservice/closeticket/?id=ID
+// Необязательный текст в теле отправляется автору как последний ответ.
+func closeTicket(w http.ResponseWriter, r *http.Request) {
+	logRequest(r)
+
+	q := r.URL.Query()
+	staff, ok := requestSupport(w, r)
+	if !ok {
+		return
+	}
+	var data struct {
+		Text string `json:"text"`
+	}
+	json.NewDecoder(r.Body).Decode(&data)
+
+	dataMu.Lock()
+	defer dataMu.Unlock()
+
+	i := findTicket(q.Get("id"))
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "обращение не найдено")
+		return
+	}
+	before := tickets[i]
+	now := time.Now()
+	if text := strings.TrimSpace(data.Text); text != "" {
+		tickets[i].Replies = append(tickets[i].Replies, TicketReply{Author: staff, Text: text, Date: now})
+		notifyTicketAuthor(tickets[i], text)
+	}
+	tickets[i].Status = TicketClosed
+	tickets[i].Updated = now
+	audit(r, staff, AuditTicketClose, tickets[i].ID, before, tickets[i])
+
+	requestLogger(r).Info("обращение закрыто", "ticket", tickets[i].ID)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tickets[i])
+}
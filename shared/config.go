@@ -0,0 +1,272 @@
+package shared
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Настройки сервера берутся из флагов командной строки, переменных окружения
+// и файла настроек (в этом порядке приоритета), остальное - по умолчанию.
+//
+// Файл настроек задается флагом -config или переменной CONFIG. Это объект
+// JSON, ключи которого совпадают с ключами настроек: {"listen": ":8081"}.
+// Значение - строка, целое число, true/false или список строк; список
+// передается настройке через запятую, как в переменных окружения.
+
+// Setting связывает настройку с ключом файла, переменной окружения и флагом
+type Setting struct {
+	Key, Env, Usage string
+	Value           *string
+	// Bool - флаг можно указать без значения, -dev-tls
+	Bool bool
+}
+
+// flagValue - строковое значение флага. Для логических настроек
+// реализует IsBoolFlag, чтобы флаг указывался без значения.
+type flagValue struct {
+	value   string
+	boolean bool
+}
+
+func (f *flagValue) String() string     { return f.value }
+func (f *flagValue) Set(s string) error { f.value = s; return nil }
+func (f *flagValue) IsBoolFlag() bool   { return f.boolean }
+
+// flagName - имя флага для ключа настройки: tls_cert - -tls-cert
+func flagName(key string) string {
+	return strings.ReplaceAll(key, "_", "-")
+}
+
+// LoadSettings записывает в настройки значения из файла, окружения и
+// аргументов командной строки. name - имя программы для -h.
+func LoadSettings(name string, args []string, settings []Setting) error {
+	// Флаги разбираются в отдельные переменные: их значения применяются
+	// последними и только если флаг указан явно.
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG"), "файл настроек в формате JSON")
+	flagValues := make(map[string]*flagValue, len(settings))
+	for _, s := range settings {
+		v := &flagValue{value: *s.Value, boolean: s.Bool}
+		fs.Var(v, flagName(s.Key), s.Usage+" ($"+s.Env+")")
+		flagValues[flagName(s.Key)] = v
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *configFile != "" {
+		values, err := ReadSettingsFile(*configFile)
+		if err != nil {
+			return err
+		}
+		for _, s := range settings {
+			if v, ok := values[s.Key]; ok {
+				*s.Value = v
+				delete(values, s.Key)
+			}
+		}
+		for key := range values {
+			return fmt.Errorf("%s: неизвестная настройка %q", *configFile, key)
+		}
+	}
+	for _, s := range settings {
+		if v, ok := os.LookupEnv(s.Env); ok {
+			*s.Value = v
+		}
+	}
+	fs.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if f.Name == flagName(s.Key) {
+				*s.Value = flagValues[f.Name].value
+			}
+		}
+	})
+	return nil
+}
+
+// ReadSettingsFile читает настройки из файла JSON.
+// Списки возвращаются через запятую, как в переменных окружения.
+func ReadSettingsFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%s: ожидается объект JSON: %v", path, err)
+	}
+
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		v, err := parseSettingValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %v", path, key, err)
+		}
+		values[key] = v
+	}
+	return values, nil
+}
+
+// parseSettingValue приводит строку, целое число, true/false или список строк
+// к текстовому виду настройки
+func parseSettingValue(value json.RawMessage) (string, error) {
+	var s string
+	var b bool
+	var n int64
+	var items []string
+	switch {
+	case string(value) == "null":
+	case json.Unmarshal(value, &s) == nil:
+		return s, nil
+	case json.Unmarshal(value, &b) == nil:
+		return strconv.FormatBool(b), nil
+	case json.Unmarshal(value, &n) == nil:
+		return strconv.FormatInt(n, 10), nil
+	case json.Unmarshal(value, &items) == nil:
+		return strings.Join(items, ","), nil
+	}
+	return "", errors.New("ожидается строка, целое число, true/false или список строк")
+}
+
+// ServerConfig - настройки HTTP-сервера и журнала, общие для обоих серверов
+type ServerConfig struct {
+	Listen  string
+	TLSCert string
+	TLSKey  string
+	// DevTLS - выпустить сертификат для localhost самостоятельно (только для разработки)
+	DevTLS    bool
+	DevTLSDir string
+	// RedirectListen - адрес, на котором HTTP-запросы перенаправляются на HTTPS
+	RedirectListen string
+	// HSTSMaxAge - срок Strict-Transport-Security, 0 - заголовок не отправляется
+	HSTSMaxAge time.Duration
+	LogLevel   string
+	LogOutput  string
+	// TrustedProxies - адреса прокси, которым верят X-Forwarded-For и X-Real-IP
+	TrustedProxies []netip.Prefix
+}
+
+// RawServerConfig - общие настройки в текстовом виде до проверки
+type RawServerConfig struct {
+	Listen, TLSCert, TLSKey                       string
+	DevTLS, DevTLSDir, RedirectListen, HSTSMaxAge string
+	LogLevel, LogOutput                           string
+	TrustedProxies                                string
+}
+
+// Settings возвращает общие настройки для LoadSettings
+func (c *RawServerConfig) Settings() []Setting {
+	return []Setting{
+		{Key: "listen", Env: "LISTEN_ADDR", Usage: "адрес, на котором сервер принимает запросы", Value: &c.Listen},
+		{Key: "tls_cert", Env: "TLS_CERT", Usage: "файл сертификата TLS (PEM)", Value: &c.TLSCert},
+		{Key: "tls_key", Env: "TLS_KEY", Usage: "файл закрытого ключа TLS (PEM)", Value: &c.TLSKey},
+		{Key: "dev_tls", Env: "DEV_TLS", Usage: "выпустить сертификат для localhost (только для разработки)", Value: &c.DevTLS, Bool: true},
+		{Key: "dev_tls_dir", Env: "DEV_TLS_DIR", Usage: "каталог сертификатов разработки", Value: &c.DevTLSDir},
+		{Key: "redirect_listen", Env: "REDIRECT_LISTEN", Usage: "адрес для перенаправления HTTP на HTTPS", Value: &c.RedirectListen},
+		{Key: "hsts_max_age", Env: "HSTS_MAX_AGE", Usage: "срок Strict-Transport-Security в секундах, 0 - не отправлять", Value: &c.HSTSMaxAge},
+		{Key: "log_level", Env: "LOG_LEVEL", Usage: "уровень журнала: debug, info, warn, error", Value: &c.LogLevel},
+		{Key: "log_output", Env: "LOG_OUTPUT", Usage: "куда писать журнал: stdout, stderr или путь к файлу", Value: &c.LogOutput},
+		{Key: "trusted_proxies", Env: "TRUSTED_PROXIES", Usage: "адреса и подсети прокси через запятую, которым верят X-Forwarded-For и X-Real-IP", Value: &c.TrustedProxies},
+	}
+}
+
+// Validate проверяет общие настройки и приводит их к рабочему виду
+func (c RawServerConfig) Validate() (ServerConfig, error) {
+	cfg := ServerConfig{
+		Listen:    c.Listen,
+		TLSCert:   c.TLSCert,
+		TLSKey:    c.TLSKey,
+		LogLevel:  c.LogLevel,
+		LogOutput: c.LogOutput,
+	}
+
+	if _, port, err := net.SplitHostPort(c.Listen); err != nil || port == "" {
+		return ServerConfig{}, fmt.Errorf("listen: некорректный адрес %q", c.Listen)
+	}
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		return ServerConfig{}, errors.New("tls_cert и tls_key задаются вместе")
+	}
+	devTLS, err := strconv.ParseBool(c.DevTLS)
+	if err != nil {
+		return ServerConfig{}, fmt.Errorf("dev_tls: ожидается true или false, получено %q", c.DevTLS)
+	}
+	if devTLS && c.TLSCert != "" {
+		return ServerConfig{}, errors.New("dev_tls несовместим с tls_cert и tls_key")
+	}
+	cfg.DevTLS = devTLS
+	cfg.DevTLSDir = c.DevTLSDir
+	if cfg.DevTLSDir == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			dir = os.TempDir()
+		}
+		cfg.DevTLSDir = filepath.Join(dir, "whitemustache", "devcerts")
+	}
+	if c.RedirectListen != "" {
+		if !devTLS && c.TLSCert == "" {
+			return ServerConfig{}, errors.New("redirect_listen требует tls_cert и tls_key или dev_tls")
+		}
+		if _, port, err := net.SplitHostPort(c.RedirectListen); err != nil || port == "" {
+			return ServerConfig{}, fmt.Errorf("redirect_listen: некорректный адрес %q", c.RedirectListen)
+		}
+		cfg.RedirectListen = c.RedirectListen
+	}
+	hsts, err := strconv.Atoi(c.HSTSMaxAge)
+	if err != nil || hsts < 0 {
+		return ServerConfig{}, fmt.Errorf("hsts_max_age: ожидается число секунд, получено %q", c.HSTSMaxAge)
+	}
+	cfg.HSTSMaxAge = time.Duration(hsts) * time.Second
+	if cfg.TrustedProxies, err = parseTrustedProxies(c.TrustedProxies); err != nil {
+		return ServerConfig{}, fmt.Errorf("trusted_proxies: %v", err)
+	}
+	for _, path := range []string{c.TLSCert, c.TLSKey} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			return ServerConfig{}, err
+		}
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		return ServerConfig{}, fmt.Errorf("log_level: %v", err)
+	}
+	return cfg, nil
+}
+
+// parseTrustedProxies разбирает адреса и подсети через запятую:
+// "10.0.0.1,192.168.0.0/16"
+func parseTrustedProxies(s string) ([]netip.Prefix, error) {
+	var proxies []netip.Prefix
+	for item := range strings.SplitSeq(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if strings.Contains(item, "/") {
+			prefix, err := netip.ParsePrefix(item)
+			if err != nil {
+				return nil, fmt.Errorf("некорректная подсеть %q", item)
+			}
+			proxies = append(proxies, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(item)
+		if err != nil {
+			return nil, fmt.Errorf("некорректный адрес %q", item)
+		}
+		proxies = append(proxies, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+	return proxies, nil
+}
@@ -0,0 +1,92 @@
+package shared
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeSettingsFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "server.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadSettingsFile(t *testing.T) {
+	path := writeSettingsFile(t, `{
+	"listen": ":8081",
+	"cors_origins": ["http://localhost:8080", "https://jobs.example.ru"],
+	"empty": [],
+	"csrf": true,
+	"hsts_max_age": 0,
+	"csp": "default-src 'self'",
+	"quoted": "кавычка \" внутри"
+}`)
+	got, err := ReadSettingsFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"listen":       ":8081",
+		"cors_origins": "http://localhost:8080,https://jobs.example.ru",
+		"empty":        "",
+		"csrf":         "true",
+		"hsts_max_age": "0",
+		"csp":          "default-src 'self'",
+		"quoted":       `кавычка " внутри`,
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("ReadSettingsFile = %q, want %q", got, want)
+	}
+}
+
+func TestReadSettingsFileErrors(t *testing.T) {
+	tests := []struct {
+		name, content, want string
+	}{
+		{"not an object", `["listen"]`, "ожидается объект JSON"},
+		{"key = value", "listen = \":80\"\n", "ожидается объект JSON"},
+		{"trailing comma", `{"listen": ":80",}`, "ожидается объект JSON"},
+		{"nested object", `{"server": {"listen": ":80"}}`, "server: ожидается строка"},
+		{"null", `{"tls_cert": null}`, "tls_cert: ожидается строка"},
+		{"fraction", `{"hsts_max_age": 1.5}`, "hsts_max_age: ожидается строка"},
+		{"list of numbers", `{"cors_origins": [1, 2]}`, "cors_origins: ожидается строка"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadSettingsFile(writeSettingsFile(t, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ReadSettingsFile error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadSettingsPrecedence(t *testing.T) {
+	path := writeSettingsFile(t, `{"listen": ":1", "log_level": "debug", "log_output": "stderr"}`)
+	raw := RawServerConfig{Listen: ":80", DevTLS: "false", LogLevel: "info", LogOutput: "stdout", HSTSMaxAge: "0"}
+	t.Setenv("CONFIG", path)
+	t.Setenv("LOG_LEVEL", "warn")
+	t.Setenv("LOG_OUTPUT", "stdout")
+	if err := LoadSettings("test", []string{"-log-output", "server.log", "-dev-tls"}, raw.Settings()); err != nil {
+		t.Fatal(err)
+	}
+	// Файл важнее значений по умолчанию, окружение - файла, флаги - окружения
+	if raw.Listen != ":1" || raw.LogLevel != "warn" || raw.LogOutput != "server.log" || raw.DevTLS != "true" {
+		t.Errorf("LoadSettings = %+v", raw)
+	}
+}
+
+func TestLoadSettingsUnknownKey(t *testing.T) {
+	path := writeSettingsFile(t, `{"listen": ":1", "upstream": "x"}`)
+	raw := RawServerConfig{}
+	err := LoadSettings("test", []string{"-config", path}, raw.Settings())
+	if err == nil || !strings.Contains(err.Error(), `неизвестная настройка "upstream"`) {
+		t.Errorf("LoadSettings error = %v", err)
+	}
+}
@@ -0,0 +1,5 @@
+// Package shared содержит общее для mock-server и сервера страниц: загрузку
+// настроек, запуск и остановку HTTP-серверов, журнал запросов, метрики HTTP,
+// проверки состояния и HTTPS с сертификатами разработки. Пакет использует
+// только стандартную библиотеку и подключается к обоим модулям директивой replace.
+package shared
@@ -0,0 +1,98 @@
+package shared
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+)
+
+// Проверки для оркестратора и прокси: /healthz - процесс жив, /readyz - сервер
+// готов принимать запросы, /version - из какой сборки запущен сервер.
+
+// BuildInfo - сведения о сборке сервера
+type BuildInfo struct {
+	Commit    string `json:"Commit"`
+	Modified  bool   `json:"Modified"`
+	BuildTime string `json:"BuildTime"`
+	GoVersion string `json:"GoVersion"`
+}
+
+// ReadBuildInfo собирает сведения о сборке. Коммит берется из данных VCS,
+// которые go build встраивает при сборке пакета из git-репозитория.
+// buildTime - время сборки, если оно задано при сборке.
+func ReadBuildInfo(buildTime string) BuildInfo {
+	info := BuildInfo{BuildTime: buildTime}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	info.GoVersion = bi.GoVersion
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			info.Commit = s.Value
+		case "vcs.modified":
+			info.Modified = s.Value == "true"
+		case "vcs.time":
+			if info.BuildTime == "" {
+				info.BuildTime = s.Value
+			}
+		}
+	}
+	return info
+}
+
+// ReadinessCheck - проверка готовности для /readyz
+type ReadinessCheck struct {
+	Name  string
+	Check func() error
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Healthz - GET /healthz
+func Healthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Readyz возвращает обработчик GET /readyz. Он отвечает 503 и списком
+// непройденных проверок, если сервер не готов. После сигнала остановки
+// проверки не выполняются: сервер отвечает 503 до завершения.
+func Readyz(checks ...ReadinessCheck) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if shuttingDown.Load() {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]any{"status": "shutting_down"})
+			return
+		}
+
+		status := http.StatusOK
+		results := map[string]string{}
+		for _, c := range checks {
+			if err := c.Check(); err != nil {
+				status = http.StatusServiceUnavailable
+				results[c.Name] = err.Error()
+				slog.Warn("проверка готовности не пройдена", "request_id", RequestID(r), "check", c.Name, "error", err)
+				continue
+			}
+			results[c.Name] = "ok"
+		}
+
+		result := "ok"
+		if status != http.StatusOK {
+			result = "unavailable"
+		}
+		writeJSON(w, status, map[string]any{"status": result, "checks": results})
+	}
+}
+
+// Version возвращает обработчик GET /version
+func Version(buildTime string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, ReadBuildInfo(buildTime))
+	}
+}
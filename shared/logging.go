@@ -0,0 +1,186 @@
+package shared
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Журнал пишется в JSON через log/slog. Уровень и приемник задаются
+// настройками log_level и log_output.
+
+const RequestIDHeader = "X-Request-ID"
+
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+type requestIDKey struct{}
+
+// SetupLogger настраивает журнал: level - debug, info, warn или error,
+// output - stdout, stderr или путь к файлу
+func SetupLogger(levelName, output string) error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(levelName)); err != nil {
+		return fmt.Errorf("log_level: %v", err)
+	}
+
+	var out io.Writer
+	switch output {
+	case "", "stdout":
+		out = os.Stdout
+	case "stderr":
+		out = os.Stderr
+	default:
+		f, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+		if err != nil {
+			return fmt.Errorf("log_output: %v", err)
+		}
+		out = f
+	}
+
+	slog.SetDefault(slog.New(slog.NewJSONHandler(out, &slog.HandlerOptions{Level: level})))
+	return nil
+}
+
+// newRequestID возвращает случайный идентификатор запроса
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// RequestID возвращает идентификатор запроса, присвоенный LoggingMiddleware
+func RequestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey{}).(string)
+	return id
+}
+
+// trustedProxies - прокси из настройки trusted_proxies, задаются в NewServers
+// до запуска серверов
+var trustedProxies []netip.Prefix
+
+func isTrustedProxy(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, p := range trustedProxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP возвращает адрес клиента без порта. Заголовкам X-Forwarded-For и
+// X-Real-IP верят, только если соединение пришло от доверенного прокси:
+// иначе клиент мог бы подставить в них любой адрес.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !isTrustedProxy(addr) {
+		return host
+	}
+
+	// Каждый прокси дописывает адрес справа, поэтому список читается с конца
+	// до первого адреса, который не принадлежит доверенному прокси
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				break
+			}
+			addr = hop.Unmap()
+			if !isTrustedProxy(addr) {
+				break
+			}
+		}
+		return addr.String()
+	}
+	if realIP, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+		return realIP.Unmap().String()
+	}
+	return host
+}
+
+// StatusRecorder запоминает код ответа и размер тела для журнала и метрик
+type StatusRecorder struct {
+	http.ResponseWriter
+	Status int
+	Bytes  int
+}
+
+func (rec *StatusRecorder) WriteHeader(status int) {
+	if rec.Status == 0 {
+		rec.Status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *StatusRecorder) Write(b []byte) (int, error) {
+	if rec.Status == 0 {
+		rec.Status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.Bytes += n
+	return n, err
+}
+
+func (rec *StatusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// LoggingMiddleware присваивает запросу X-Request-ID (или берет его из заголовка
+// клиента) и после ответа пишет в журнал метод, путь, код, время и размер ответа.
+// Тело запроса не записывается. Параметры запроса записываются, только если
+// задана query: она возвращает их строкой без персональных данных.
+func LoggingMiddleware(next http.Handler, query func(url.Values) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		r = r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id))
+
+		start := time.Now()
+		rec := &StatusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if rec.Status == 0 {
+			rec.Status = http.StatusOK
+		}
+
+		level := slog.LevelInfo
+		if rec.Status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		} else if rec.Status >= http.StatusBadRequest {
+			level = slog.LevelWarn
+		}
+		attrs := []any{
+			"request_id", id,
+			"method", r.Method,
+			"path", r.URL.Path,
+		}
+		if query != nil {
+			attrs = append(attrs, "query", query(r.URL.Query()))
+		}
+		attrs = append(attrs,
+			"status", rec.Status,
+			"latency_ms", float64(time.Since(start).Microseconds())/1000,
+			"bytes", rec.Bytes,
+			"ip", ClientIP(r),
+		)
+		slog.Log(r.Context(), level, "запрос обработан", attrs...)
+	})
+}
@@ -0,0 +1,46 @@
+package shared
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies, err := parseTrustedProxies("10.0.0.1, 192.168.0.0/16")
+	if err != nil {
+		t.Fatal(err)
+	}
+	trustedProxies = proxies
+	t.Cleanup(func() { trustedProxies = nil })
+
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded string
+		realIP    string
+		want      string
+	}{
+		{"без прокси", "203.0.113.7:5000", "", "", "203.0.113.7"},
+		{"заголовки от клиента не учитываются", "203.0.113.7:5000", "198.51.100.1", "198.51.100.2", "203.0.113.7"},
+		{"доверенный прокси", "10.0.0.1:5000", "198.51.100.1", "", "198.51.100.1"},
+		{"подставленный клиентом адрес слева", "10.0.0.1:5000", "1.1.1.1, 198.51.100.1", "", "198.51.100.1"},
+		{"цепочка доверенных прокси", "10.0.0.1:5000", "198.51.100.1, 192.168.1.5", "", "198.51.100.1"},
+		{"X-Real-IP", "192.168.3.4:5000", "", "198.51.100.3", "198.51.100.3"},
+		{"некорректный заголовок", "10.0.0.1:5000", "unknown", "", "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remote
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if got := ClientIP(r); got != tt.want {
+				t.Errorf("ClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
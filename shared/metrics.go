@@ -0,0 +1,122 @@
+package shared
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Метрики отдаются на /metrics в текстовом формате Prometheus. Здесь - счетчики
+// HTTP-запросов, показатели самих серверов выводит каждый сервер.
+
+// Границы корзин гистограммы времени обработки запроса, в секундах
+var latencyBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+type requestSeries struct {
+	route, method, status string
+}
+
+type histogram struct {
+	counts []uint64 // по одной на каждую границу latencyBuckets
+	sum    float64
+	count  uint64
+}
+
+func (h *histogram) observe(v float64) {
+	for i, le := range latencyBuckets {
+		if v <= le {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+var httpMetrics struct {
+	sync.Mutex
+	requests  map[requestSeries]uint64
+	latencies map[string]*histogram
+}
+
+// MetricsMiddleware считает запросы по маршрутам. Маршрут берется из шаблона
+// ServeMux, чтобы номера и идентификаторы в пути не плодили серии.
+func MetricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &StatusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if rec.Status == 0 {
+			rec.Status = http.StatusOK
+		}
+
+		route := r.Pattern
+		if route == "" {
+			route = "other"
+		}
+		key := requestSeries{route: route, method: r.Method, status: strconv.Itoa(rec.Status)}
+		elapsed := time.Since(start).Seconds()
+
+		httpMetrics.Lock()
+		defer httpMetrics.Unlock()
+		if httpMetrics.requests == nil {
+			httpMetrics.requests = map[requestSeries]uint64{}
+			httpMetrics.latencies = map[string]*histogram{}
+		}
+		httpMetrics.requests[key]++
+		h, ok := httpMetrics.latencies[route]
+		if !ok {
+			h = &histogram{counts: make([]uint64, len(latencyBuckets))}
+			httpMetrics.latencies[route] = h
+		}
+		h.observe(elapsed)
+	})
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// EscapeLabel экранирует значение метки
+func EscapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+// WriteMetricHeader выводит описание и тип метрики
+func WriteMetricHeader(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// WriteHTTPMetrics выводит счетчики запросов и гистограммы времени обработки
+func WriteHTTPMetrics(w io.Writer) {
+	httpMetrics.Lock()
+	defer httpMetrics.Unlock()
+
+	WriteMetricHeader(w, "http_requests_total", "counter", "Количество HTTP-запросов по маршруту, методу и коду ответа.")
+	keys := slices.SortedFunc(maps.Keys(httpMetrics.requests), func(a, b requestSeries) int {
+		return strings.Compare(a.route+" "+a.method+" "+a.status, b.route+" "+b.method+" "+b.status)
+	})
+	for _, k := range keys {
+		fmt.Fprintf(w, "http_requests_total{route=\"%s\",method=\"%s\",status=\"%s\"} %d\n",
+			EscapeLabel(k.route), EscapeLabel(k.method), k.status, httpMetrics.requests[k])
+	}
+
+	WriteMetricHeader(w, "http_request_duration_seconds", "histogram", "Время обработки HTTP-запроса по маршруту.")
+	for _, route := range slices.Sorted(maps.Keys(httpMetrics.latencies)) {
+		h := httpMetrics.latencies[route]
+		label := EscapeLabel(route)
+		for i, le := range latencyBuckets {
+			fmt.Fprintf(w, "http_request_duration_seconds_bucket{route=\"%s\",le=\"%s\"} %d\n", label, formatFloat(le), h.counts[i])
+		}
+		fmt.Fprintf(w, "http_request_duration_seconds_bucket{route=\"%s\",le=\"+Inf\"} %d\n", label, h.count)
+		fmt.Fprintf(w, "http_request_duration_seconds_sum{route=\"%s\"} %s\n", label, formatFloat(h.sum))
+		fmt.Fprintf(w, "http_request_duration_seconds_count{route=\"%s\"} %d\n", label, h.count)
+	}
+}
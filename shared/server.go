@@ -0,0 +1,125 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Ограничения HTTP-сервера. ReadTimeout задает каждый сервер сам: mock-server
+// принимает загрузку резюме, серверу страниц хватает меньшего.
+const (
+	readHeaderTimeout = 5 * time.Second
+	writeTimeout      = 30 * time.Second
+	idleTimeout       = 120 * time.Second
+	maxHeaderBytes    = 64 << 10
+	// shutdownTimeout - сколько ждать завершения начатых запросов при остановке
+	shutdownTimeout = 20 * time.Second
+)
+
+// shuttingDown выставляется при остановке сервера: пока начатые запросы
+// дорабатывают, /readyz на открытых соединениях отвечает 503.
+var shuttingDown atomic.Bool
+
+func newServer(addr string, handler http.Handler, readTimeout time.Duration) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+		MaxHeaderBytes:    maxHeaderBytes,
+		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
+	}
+}
+
+// NewServers создает основной сервер с журналом запросов и метриками и, если
+// задан redirect_listen, сервер перенаправления на HTTPS. Сертификат берется
+// из tls_cert и tls_key или выпускается в режиме dev_tls. query - параметры
+// запроса для журнала, см. LoggingMiddleware. Здесь же запоминаются
+// доверенные прокси для ClientIP.
+func NewServers(cfg ServerConfig, handler http.Handler, readTimeout time.Duration, query func(url.Values) string) ([]*http.Server, error) {
+	trustedProxies = cfg.TrustedProxies
+	certFile, keyFile := cfg.TLSCert, cfg.TLSKey
+	if cfg.DevTLS {
+		var err error
+		if certFile, keyFile, err = EnsureDevCertificate(cfg.DevTLSDir); err != nil {
+			return nil, err
+		}
+	}
+	// В режиме разработки HSTS не отправляется: браузер запомнил бы его для
+	// localhost целиком, и остальные локальные проекты перестали бы открываться по HTTP
+	if certFile != "" && cfg.HSTSMaxAge > 0 && !cfg.DevTLS {
+		handler = HSTSMiddleware(cfg.HSTSMaxAge, handler)
+	}
+
+	srv := newServer(cfg.Listen, LoggingMiddleware(MetricsMiddleware(handler), query), readTimeout)
+	if certFile != "" {
+		var err error
+		if srv.TLSConfig, err = NewTLSConfig(certFile, keyFile); err != nil {
+			return nil, err
+		}
+	}
+	servers := []*http.Server{srv}
+	if cfg.RedirectListen != "" {
+		servers = append(servers, newServer(cfg.RedirectListen, LoggingMiddleware(RedirectToHTTPS(cfg.Listen), query), readTimeout))
+	}
+	return servers, nil
+}
+
+// RunServers обслуживает запросы до SIGINT или SIGTERM, затем перестает
+// принимать соединения, дожидается начатых запросов и вызывает onShutdown,
+// если он задан. Серверы с TLSConfig принимают запросы по HTTPS.
+func RunServers(onShutdown func(), servers ...*http.Server) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	errc := make(chan error, len(servers))
+	for _, srv := range servers {
+		go func() {
+			if srv.TLSConfig != nil {
+				errc <- srv.ListenAndServeTLS("", "")
+				return
+			}
+			errc <- srv.ListenAndServe()
+		}()
+	}
+
+	var serveErr error
+	select {
+	case serveErr = <-errc:
+	case <-ctx.Done():
+	}
+	stop()
+
+	if serveErr == nil {
+		slog.Info("получен сигнал остановки, завершение запросов", "timeout", shutdownTimeout.String())
+	}
+	shuttingDown.Store(true)
+	// Серверы останавливаются одновременно, у каждого свой таймаут: иначе
+	// долгие запросы первого сервера отнимали бы время у следующих
+	var wg sync.WaitGroup
+	for _, srv := range servers {
+		wg.Go(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+			if err := srv.Shutdown(ctx); errors.Is(err, context.DeadlineExceeded) {
+				slog.Warn("не все запросы завершились до остановки", "addr", srv.Addr)
+				srv.Close()
+			}
+		})
+	}
+	wg.Wait()
+	if onShutdown != nil {
+		onShutdown()
+	}
+	return serveErr
+}
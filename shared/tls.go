@@ -0,0 +1,251 @@
+package shared
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// В режиме разработки (dev_tls) сервер сам выпускает сертификат для localhost,
+// подписанный локальным корневым сертификатом. Оба сертификата хранятся в
+// каталоге dev_tls_dir и переиспользуются при следующих запусках, поэтому
+// корневой сертификат ca.pem достаточно один раз добавить в доверенные.
+
+const (
+	devCAFile      = "ca.pem"
+	devCAKeyFile   = "ca-key.pem"
+	devCertFile    = "localhost.pem"
+	devCertKeyFile = "localhost-key.pem"
+
+	devCAValidity = 10 * 365 * 24 * time.Hour
+	// Браузеры не принимают сертификаты сроком больше 398 дней
+	devCertValidity = 397 * 24 * time.Hour
+	// devCertRenewBefore - за сколько до истечения сертификат выпускается заново
+	devCertRenewBefore = 30 * 24 * time.Hour
+)
+
+// devCertHosts - имена, для которых выпускается сертификат разработки
+var devCertHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+// EnsureDevCertificate возвращает пути к сертификату и ключу для localhost,
+// при необходимости выпуская корневой сертификат и сертификат сервера
+func EnsureDevCertificate(dir string) (certFile, keyFile string, err error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", "", err
+	}
+
+	ca, caKey, err := loadKeyPair(filepath.Join(dir, devCAFile), filepath.Join(dir, devCAKeyFile))
+	if errors.Is(err, os.ErrNotExist) {
+		ca, caKey, err = createDevCA(dir)
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("корневой сертификат разработки: %v", err)
+	}
+
+	certFile, keyFile = filepath.Join(dir, devCertFile), filepath.Join(dir, devCertKeyFile)
+	cert, _, err := loadKeyPair(certFile, keyFile)
+	if err == nil && cert.CheckSignatureFrom(ca) == nil && time.Until(cert.NotAfter) > devCertRenewBefore {
+		return certFile, keyFile, nil
+	}
+	if err := createDevCertificate(dir, ca, caKey); err != nil {
+		return "", "", fmt.Errorf("сертификат разработки: %v", err)
+	}
+	return certFile, keyFile, nil
+}
+
+func createDevCA(dir string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          randomSerial(),
+		Subject:               pkix.Name{Organization: []string{"WhiteMustache"}, CommonName: "WhiteMustache Dev CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(devCAValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := writeKeyPair(filepath.Join(dir, devCAFile), filepath.Join(dir, devCAKeyFile), der, key); err != nil {
+		return nil, nil, err
+	}
+	slog.Warn("выпущен корневой сертификат разработки, добавьте его в доверенные",
+		"path", filepath.Join(dir, devCAFile))
+	ca, err := x509.ParseCertificate(der)
+	return ca, key, err
+}
+
+func createDevCertificate(dir string, ca *x509.Certificate, caKey *ecdsa.PrivateKey) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	template := &x509.Certificate{
+		SerialNumber: randomSerial(),
+		Subject:      pkix.Name{Organization: []string{"WhiteMustache"}, CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(devCertValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, h := range devCertHosts {
+		if ip := net.ParseIP(h); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, h)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return err
+	}
+	slog.Info("выпущен сертификат разработки", "path", filepath.Join(dir, devCertFile))
+	return writeKeyPair(filepath.Join(dir, devCertFile), filepath.Join(dir, devCertKeyFile), der, key)
+}
+
+func randomSerial() *big.Int {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		panic(err)
+	}
+	return serial
+}
+
+// loadKeyPair читает сертификат и ключ ECDSA в формате PEM
+func loadKeyPair(certFile, keyFile string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	certPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	certBlock, _ := pem.Decode(certPEM)
+	keyBlock, _ := pem.Decode(keyPEM)
+	if certBlock == nil || keyBlock == nil {
+		return nil, nil, fmt.Errorf("%s: некорректный PEM", certFile)
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, key, nil
+}
+
+// writeKeyPair сохраняет сертификат и ключ. Файлы пишутся через временные,
+// чтобы второй сервер, запущенный одновременно, не прочитал их наполовину.
+func writeKeyPair(certFile, keyFile string, der []byte, key *ecdsa.PrivateKey) error {
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		return err
+	}
+	return writeFileAtomic(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644)
+}
+
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// DevCATransport возвращает транспорт, который кроме системных доверяет
+// корневому сертификату разработки из dir
+func DevCATransport(dir string) (*http.Transport, error) {
+	data, err := os.ReadFile(filepath.Join(dir, devCAFile))
+	if err != nil {
+		return nil, err
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("%s: некорректный PEM", devCAFile)
+	}
+	return &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}, nil
+}
+
+// NewTLSConfig загружает сертификат сервера
+func NewTLSConfig(certFile, keyFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, nil
+}
+
+// HSTSMiddleware запрещает браузеру обращаться к серверу по HTTP
+// в течение maxAge после первого ответа по HTTPS
+func HSTSMiddleware(maxAge time.Duration, next http.Handler) http.Handler {
+	value := "max-age=" + strconv.Itoa(int(maxAge.Seconds()))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil {
+			w.Header().Set("Strict-Transport-Security", value)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RedirectToHTTPS перенаправляет запросы по HTTP на тот же путь по HTTPS.
+// tlsAddr - адрес HTTPS-сервера, из него берется порт.
+func RedirectToHTTPS(tlsAddr string) http.Handler {
+	_, port, _ := net.SplitHostPort(tlsAddr)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := strings.Trim(r.Host, "[]")
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		}
+		if port != "443" {
+			host = net.JoinHostPort(host, port)
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		u := *r.URL
+		u.Scheme, u.Host = "https", host
+		// 308 сохраняет метод и тело запроса
+		http.Redirect(w, r, u.String(), http.StatusPermanentRedirect)
+	})
+}